@@ -7,19 +7,20 @@ import (
 	"reflect"
 )
 
-func makeArrayCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeArrayCodec(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
 	// array type must have items
 	itemSchema, ok := schemaMap["items"]
 	if !ok {
 		return nil, fmt.Errorf("Array ought to have items key")
 	}
-	itemCodec, err := buildCodec(st, enclosingNamespace, itemSchema)
+	itemCodec, err := buildCodec(st, config, enclosingNamespace, itemSchema)
 	if err != nil {
 		return nil, fmt.Errorf("Array items ought to be valid Avro type: %s", err)
 	}
 
 	return &Codec{
 		typeName: &name{"array", nullNamespace},
+		items:    itemCodec,
 		nativeFromBinary: func(buf []byte) (interface{}, []byte, error) {
 			var value interface{}
 			var err error
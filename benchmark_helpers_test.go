@@ -1,8 +1,11 @@
 package goavro_test
 
 import (
+	"bytes"
 	"io/ioutil"
 	"testing"
+
+	v5 "github.com/karrick/goavro"
 )
 
 func benchmarkNewCodecUsingV4(b *testing.B, avscPath string) {
@@ -148,3 +151,120 @@ func benchmarkNativeFromTextualUsingV5(b *testing.B, avroPath string) {
 		_ = nativeFromTextUsingV5(b, codec, textData)
 	}
 }
+
+func benchmarkOCFWriterAppend(b *testing.B, avroPath, compressionName string, compressionLevel int) {
+	avroBlob, err := ioutil.ReadFile(avroPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	nativeData, codec := nativeFromAvroUsingV5(b, avroBlob)
+	ocfw, err := v5.NewOCFWriter(v5.OCFConfig{
+		W:                ioutil.Discard,
+		Codec:            codec,
+		CompressionName:  compressionName,
+		CompressionLevel: compressionLevel,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = ocfw.Append(nativeData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkOCFReaderRead(b *testing.B, avroPath string) {
+	avroBlob, err := ioutil.ReadFile(avroPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ocfr, err := v5.NewOCFReader(bytes.NewReader(avroBlob))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for ocfr.Scan() {
+			if _, err = ocfr.Read(); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err = ocfr.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkOCFReaderReadInto(b *testing.B, avroPath string) {
+	avroBlob, err := ioutil.ReadFile(avroPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var person struct {
+		ID                 int64
+		First, Last, Phone string
+		Age                int32
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ocfr, err := v5.NewOCFReader(bytes.NewReader(avroBlob))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for ocfr.Scan() {
+			if err = ocfr.ReadInto(&person); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err = ocfr.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkOCFWriterAppendStructs(b *testing.B, avroPath, compressionName string) {
+	avroBlob, err := ioutil.ReadFile(avroPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ocfr, err := v5.NewOCFReader(bytes.NewReader(avroBlob))
+	if err != nil {
+		b.Fatal(err)
+	}
+	type person struct {
+		ID                 int64
+		First, Last, Phone string
+		Age                int32
+	}
+	var people []person
+	for ocfr.Scan() {
+		var p person
+		if err = ocfr.ReadInto(&p); err != nil {
+			b.Fatal(err)
+		}
+		people = append(people, p)
+	}
+	if err = ocfr.Err(); err != nil {
+		b.Fatal(err)
+	}
+	ocfw, err := v5.NewOCFWriter(v5.OCFConfig{
+		W:               ioutil.Discard,
+		Codec:           ocfr.Codec(),
+		CompressionName: compressionName,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = ocfw.AppendStructs(people); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package goavro_test
 
-import "testing"
+import (
+	"compress/flate"
+	"testing"
+
+	v5 "github.com/karrick/goavro"
+)
 
 func BenchmarkNewCodecUsingV4(b *testing.B) {
 	benchmarkNewCodecUsingV4(b, "fixtures/quickstop.avsc")
@@ -49,3 +54,35 @@ func BenchmarkNativeFromTextualUsingJSONUnmarshal(b *testing.B) {
 func BenchmarkNativeFromTextualUsingV5(b *testing.B) {
 	benchmarkNativeFromTextualUsingV5(b, "fixtures/quickstop-null.avro")
 }
+
+func BenchmarkOCFReaderReadUsingV5(b *testing.B) {
+	benchmarkOCFReaderRead(b, "fixtures/quickstop-null.avro")
+}
+
+func BenchmarkOCFReaderReadIntoUsingV5(b *testing.B) {
+	benchmarkOCFReaderReadInto(b, "fixtures/quickstop-null.avro")
+}
+
+func BenchmarkOCFWriterAppendNull(b *testing.B) {
+	benchmarkOCFWriterAppend(b, "fixtures/quickstop-null.avro", v5.CompressionNullLabel, 0)
+}
+
+func BenchmarkOCFWriterAppendStructsNull(b *testing.B) {
+	benchmarkOCFWriterAppendStructs(b, "fixtures/quickstop-null.avro", v5.CompressionNullLabel)
+}
+
+func BenchmarkOCFWriterAppendDeflateDefaultCompression(b *testing.B) {
+	benchmarkOCFWriterAppend(b, "fixtures/quickstop-null.avro", v5.CompressionDeflateLabel, 0)
+}
+
+func BenchmarkOCFWriterAppendDeflateBestSpeed(b *testing.B) {
+	benchmarkOCFWriterAppend(b, "fixtures/quickstop-null.avro", v5.CompressionDeflateLabel, flate.BestSpeed)
+}
+
+func BenchmarkOCFWriterAppendDeflateBestCompression(b *testing.B) {
+	benchmarkOCFWriterAppend(b, "fixtures/quickstop-null.avro", v5.CompressionDeflateLabel, flate.BestCompression)
+}
+
+func BenchmarkOCFWriterAppendSnappy(b *testing.B) {
+	benchmarkOCFWriterAppend(b, "fixtures/quickstop-null.avro", v5.CompressionSnappyLabel, 0)
+}
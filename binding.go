@@ -0,0 +1,552 @@
+package goavro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// bindingDecoder decodes one binary encoded datum value from buf directly into
+// v, which must be settable, and returns buf with the decoded bytes consumed.
+type bindingDecoder func(buf []byte, v reflect.Value) ([]byte, error)
+
+// bindingKey identifies the binding of a codec to a Go type.
+type bindingKey struct {
+	c *Codec
+	t reflect.Type
+}
+
+// decoderBinder binds codecs to Go types, binding each combination of codec
+// and Go type only once, so recursive schemas may be bound to recursive Go
+// types.
+type decoderBinder struct {
+	bound  map[bindingKey]*bindingDecoder
+	failed map[bindingKey]error
+}
+
+// bindDecoder returns a function that decodes binary data encoded by c
+// directly into a Go value of type t, without first decoding it to native Go
+// data, or an error when the schema of c cannot be bound to t.
+//
+// Avro types are bound to Go types as follows. Any Avro type may be bound to
+// an empty interface, in which case the datum value is decoded as native Go
+// data. Any Avro type other than null may be bound to a pointer to a type to
+// which it may be bound, in which case the pointer is allocated when nil.
+//
+//     null: any Go type, which is set to its zero value
+//     boolean: bool
+//     int, long: any integer or floating point type
+//     float, double: any floating point type
+//     bytes, string: []byte or string
+//     fixed: []byte, string, or [N]byte, where N is the fixed size
+//     enum: string, set to the symbol, or any integer type, set to its index
+//     array: slice of a type to which the items may be bound
+//     map: map with string keys and values of a type to which the values may
+//         be bound
+//     record: struct, whose exported fields are bound to the record fields
+//         having the name in their avro struct tag, or, without a tag, having
+//         the same name, ignoring case
+//     union: a type to which at least one non-null union member may be bound,
+//         or a pointer to such a type, set to nil when the datum is null
+//
+// Record fields without a corresponding struct field are decoded and
+// discarded, and struct fields without a corresponding record field, or having
+// an avro struct tag of "-", are left unchanged.
+func bindDecoder(c *Codec, t reflect.Type) (bindingDecoder, error) {
+	b := &decoderBinder{
+		bound:  make(map[bindingKey]*bindingDecoder),
+		failed: make(map[bindingKey]error),
+	}
+	return b.bind(c, t)
+}
+
+func (b *decoderBinder) bind(c *Codec, t reflect.Type) (bindingDecoder, error) {
+	key := bindingKey{c, t}
+	if err, ok := b.failed[key]; ok {
+		return nil, err
+	}
+	if p, ok := b.bound[key]; ok {
+		// NOTE: The decoder may still be being bound when the schema is
+		// recursive, so defer looking it up until it is invoked.
+		return func(buf []byte, v reflect.Value) ([]byte, error) { return (*p)(buf, v) }, nil
+	}
+	p := new(bindingDecoder)
+	b.bound[key] = p
+	d, err := b.bindType(c, t)
+	if err != nil {
+		b.failed[key] = err
+		d = func([]byte, reflect.Value) ([]byte, error) { return nil, err }
+	}
+	*p = d
+	return d, err
+}
+
+func (b *decoderBinder) bindType(c *Codec, t reflect.Type) (bindingDecoder, error) {
+	avroType := c.avroType()
+
+	switch {
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			datum, buf, err := c.nativeFromBinary(buf)
+			if err != nil {
+				return nil, err
+			}
+			if datum == nil {
+				v.Set(reflect.Zero(v.Type()))
+			} else {
+				v.Set(reflect.ValueOf(datum))
+			}
+			return buf, nil
+		}, nil
+	case avroType == "null":
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			v.Set(reflect.Zero(v.Type()))
+			return buf, nil
+		}, nil
+	case t.Kind() == reflect.Ptr && avroType != "union":
+		elemDecoder, err := b.bind(c, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return elemDecoder(buf, v.Elem())
+		}, nil
+	}
+
+	switch avroType {
+	case "boolean":
+		if t.Kind() == reflect.Bool {
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				datum, buf, err := booleanNativeFromBinary(buf)
+				if err != nil {
+					return nil, err
+				}
+				v.SetBool(datum.(bool))
+				return buf, nil
+			}, nil
+		}
+	case "int", "long":
+		if d := bindIntegerDecoder(t, avroType, longFromBinary); d != nil {
+			return d, nil
+		}
+	case "float", "double":
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			size := floatEncodedLength
+			if avroType == "double" {
+				size = doubleEncodedLength
+			}
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				if len(buf) < size {
+					return nil, fmt.Errorf("cannot decode binary %s: %s", avroType, io.ErrShortBuffer)
+				}
+				if size == floatEncodedLength {
+					v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(buf))))
+				} else {
+					v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(buf)))
+				}
+				return buf[size:], nil
+			}, nil
+		}
+	case "bytes", "string":
+		if d := bindBytesDecoder(t, func(buf []byte) ([]byte, []byte, error) {
+			someBytes, buf, err := bytesFromBinary(buf)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot decode binary %s: %s", avroType, err)
+			}
+			return someBytes, buf, nil
+		}); d != nil {
+			return d, nil
+		}
+	case "fixed":
+		size := c.size
+		fixedFromBinary := func(buf []byte) ([]byte, []byte, error) {
+			if size > len(buf) {
+				return nil, nil, fmt.Errorf("cannot decode binary fixed %q: schema size exceeds remaining buffer size: %d > %d (short buffer)", c.typeName, size, len(buf))
+			}
+			return buf[:size], buf[size:], nil
+		}
+		if t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 && t.Len() == size {
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				someBytes, buf, err := fixedFromBinary(buf)
+				if err != nil {
+					return nil, err
+				}
+				reflect.Copy(v, reflect.ValueOf(someBytes))
+				return buf, nil
+			}, nil
+		}
+		if d := bindBytesDecoder(t, fixedFromBinary); d != nil {
+			return d, nil
+		}
+	case "enum":
+		symbols := c.symbols
+		indexFromBinary := func(buf []byte) (int64, []byte, error) {
+			index, buf, err := longFromBinary(buf)
+			if err != nil {
+				return 0, nil, fmt.Errorf("cannot decode binary enum %q index: %s", c.typeName, err)
+			}
+			if index < 0 || index >= int64(len(symbols)) {
+				return 0, nil, fmt.Errorf("cannot decode binary enum %q: index ought to be between 0 and %d; read index: %d", c.typeName, len(symbols)-1, index)
+			}
+			return index, buf, nil
+		}
+		if t.Kind() == reflect.String {
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				index, buf, err := indexFromBinary(buf)
+				if err != nil {
+					return nil, err
+				}
+				v.SetString(symbols[index])
+				return buf, nil
+			}, nil
+		}
+		if d := bindIntegerDecoder(t, "enum", indexFromBinary); d != nil {
+			return d, nil
+		}
+	case "array":
+		if t.Kind() == reflect.Slice {
+			itemDecoder, err := b.bind(c.items, t.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("cannot bind Avro array items to Go %s: %s", t.Elem(), err)
+			}
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				items := reflect.MakeSlice(v.Type(), 0, 0)
+				for {
+					blockCount, rest, err := blockCountFromBinary(buf, "array")
+					if err != nil {
+						return nil, err
+					}
+					if buf = rest; blockCount == 0 {
+						break
+					}
+					if n := items.Len() + int(blockCount); n > items.Cap() {
+						grown := reflect.MakeSlice(v.Type(), items.Len(), n)
+						reflect.Copy(grown, items)
+						items = grown
+					}
+					for i := int64(0); i < blockCount; i++ {
+						items = items.Slice(0, items.Len()+1)
+						if buf, err = itemDecoder(buf, items.Index(items.Len()-1)); err != nil {
+							return nil, fmt.Errorf("cannot decode binary array item %d: %s", i+1, err)
+						}
+					}
+				}
+				v.Set(items)
+				return buf, nil
+			}, nil
+		}
+	case "map":
+		if t.Kind() == reflect.Map && t.Key().Kind() == reflect.String {
+			valueDecoder, err := b.bind(c.items, t.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("cannot bind Avro map values to Go %s: %s", t.Elem(), err)
+			}
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				values := reflect.MakeMap(v.Type())
+				key := reflect.New(v.Type().Key()).Elem()
+				value := reflect.New(v.Type().Elem()).Elem()
+				for {
+					blockCount, rest, err := blockCountFromBinary(buf, "map")
+					if err != nil {
+						return nil, err
+					}
+					if buf = rest; blockCount == 0 {
+						break
+					}
+					for i := int64(0); i < blockCount; i++ {
+						var someBytes []byte
+						if someBytes, buf, err = bytesFromBinary(buf); err != nil {
+							return nil, fmt.Errorf("cannot decode binary map key: %s", err)
+						}
+						key.SetString(string(someBytes))
+						value.Set(reflect.Zero(value.Type()))
+						if buf, err = valueDecoder(buf, value); err != nil {
+							return nil, fmt.Errorf("cannot decode binary map value for key %q: %s", key, err)
+						}
+						values.SetMapIndex(key, value)
+					}
+				}
+				v.Set(values)
+				return buf, nil
+			}, nil
+		}
+	case "record":
+		if t.Kind() == reflect.Struct {
+			return b.bindRecord(c, t)
+		}
+	case "union":
+		return b.bindUnion(c, t)
+	}
+
+	return nil, fmt.Errorf("cannot bind Avro %s to Go %s", describeBinding(c), t)
+}
+
+// bindRecord binds a record codec to a struct type.
+func (b *decoderBinder) bindRecord(c *Codec, t reflect.Type) (bindingDecoder, error) {
+	type fieldBinding struct {
+		index   int            // index of struct field
+		decoder bindingDecoder // decodes into struct field
+		skip    *Codec         // when not nil, decodes record field to be discarded
+	}
+	fieldBindings := make([]fieldBinding, len(c.fields))
+	for i, field := range c.fields {
+		index := structFieldIndex(t, field.name)
+		if index < 0 {
+			fieldBindings[i] = fieldBinding{skip: field.codec}
+			continue
+		}
+		decoder, err := b.bind(field.codec, t.Field(index).Type)
+		if err != nil {
+			return nil, fmt.Errorf("cannot bind Avro record %q field %q to Go %s field %s: %s", c.typeName, field.name, t, t.Field(index).Name, err)
+		}
+		fieldBindings[i] = fieldBinding{index: index, decoder: decoder}
+	}
+
+	return func(buf []byte, v reflect.Value) ([]byte, error) {
+		var err error
+		for i, fb := range fieldBindings {
+			if fb.skip != nil {
+				_, buf, err = fb.skip.nativeFromBinary(buf)
+			} else {
+				buf, err = fb.decoder(buf, v.Field(fb.index))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode binary record %q field %q: %s", c.typeName, c.fields[i].name, err)
+			}
+		}
+		return buf, nil
+	}, nil
+}
+
+// bindUnion binds a union codec to a Go type, to which the non-null union
+// members that may be bound to it are bound. When the Go type is a pointer,
+// the union members are bound to its element type.
+func (b *decoderBinder) bindUnion(c *Codec, t reflect.Type) (bindingDecoder, error) {
+	memberType, indirect := t, false
+	if t.Kind() == reflect.Ptr {
+		memberType, indirect = t.Elem(), true
+	}
+
+	memberDecoders := make([]bindingDecoder, len(c.members))
+	var bound int
+	var firstErr error
+	for i, member := range c.members {
+		if member.avroType() == "null" {
+			continue
+		}
+		decoder, err := b.bind(member, memberType)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		memberDecoders[i] = decoder
+		bound++
+	}
+	if bound == 0 {
+		if firstErr == nil {
+			return nil, fmt.Errorf("cannot bind Avro %s to Go %s", describeBinding(c), t)
+		}
+		return nil, firstErr
+	}
+
+	return func(buf []byte, v reflect.Value) ([]byte, error) {
+		index, buf, err := longFromBinary(buf)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(c.members)) {
+			return nil, fmt.Errorf("cannot decode binary union: index ought to be between 0 and %d; read index: %d", len(c.members)-1, index)
+		}
+		member := c.members[index]
+		if member.avroType() == "null" {
+			v.Set(reflect.Zero(v.Type()))
+			return buf, nil
+		}
+		decoder := memberDecoders[index]
+		if decoder == nil {
+			return nil, fmt.Errorf("cannot decode binary union item %d: cannot bind Avro %s to Go %s", index+1, describeBinding(member), memberType)
+		}
+		if indirect {
+			if v.IsNil() {
+				v.Set(reflect.New(memberType))
+			}
+			v = v.Elem()
+		}
+		if buf, err = decoder(buf, v); err != nil {
+			return nil, fmt.Errorf("cannot decode binary union item %d: %s", index+1, err)
+		}
+		return buf, nil
+	}, nil
+}
+
+// bindIntegerDecoder returns a decoder which sets an integer or floating point
+// Go value to the integer decoded by integerFromBinary, checking for overflow,
+// or nil when t is not such a type.
+func bindIntegerDecoder(t reflect.Type, avroType string, integerFromBinary func([]byte) (int64, []byte, error)) bindingDecoder {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			value, buf, err := integerFromBinary(buf)
+			if err != nil {
+				return nil, err
+			}
+			if v.OverflowInt(value) {
+				return nil, fmt.Errorf("cannot decode binary %s: value overflows Go %s: %d", avroType, v.Type(), value)
+			}
+			v.SetInt(value)
+			return buf, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			value, buf, err := integerFromBinary(buf)
+			if err != nil {
+				return nil, err
+			}
+			if value < 0 || v.OverflowUint(uint64(value)) {
+				return nil, fmt.Errorf("cannot decode binary %s: value overflows Go %s: %d", avroType, v.Type(), value)
+			}
+			v.SetUint(uint64(value))
+			return buf, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			value, buf, err := integerFromBinary(buf)
+			if err != nil {
+				return nil, err
+			}
+			v.SetFloat(float64(value))
+			return buf, nil
+		}
+	}
+	return nil
+}
+
+// bindBytesDecoder returns a decoder which sets a []byte or string Go value to
+// a copy of the bytes decoded by bytesFromBinary, or nil when t is not such a
+// type.
+func bindBytesDecoder(t reflect.Type, bytesFromBinary func([]byte) ([]byte, []byte, error)) bindingDecoder {
+	switch {
+	case t.Kind() == reflect.String:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			someBytes, buf, err := bytesFromBinary(buf)
+			if err != nil {
+				return nil, err
+			}
+			v.SetString(string(someBytes))
+			return buf, nil
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			someBytes, buf, err := bytesFromBinary(buf)
+			if err != nil {
+				return nil, err
+			}
+			// NOTE: Copy the bytes, because buf may be reused once decoded.
+			v.SetBytes(append([]byte(nil), someBytes...))
+			return buf, nil
+		}
+	}
+	return nil
+}
+
+// structFieldIndex returns the index of the exported field of t bound to the
+// record field named name, or -1 when there is none. A struct field whose avro
+// tag names the record field is preferred, followed by an untagged struct
+// field with the same name, then one with the same name ignoring case.
+func structFieldIndex(t reflect.Type, name string) int {
+	foldIndex := -1
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+		if tag, ok := sf.Tag.Lookup("avro"); ok {
+			if tagName := strings.Split(tag, ",")[0]; tagName == name {
+				return i
+			} else if tagName != "" {
+				continue // tag names another record field, or is "-"
+			}
+		}
+		if sf.Name == name {
+			return i
+		}
+		if foldIndex < 0 && strings.EqualFold(sf.Name, name) {
+			foldIndex = i
+		}
+	}
+	return foldIndex
+}
+
+// describeBinding returns a description of the Avro type of c for binding
+// error messages.
+func describeBinding(c *Codec) string {
+	avroType := c.avroType()
+	switch avroType {
+	case "enum", "fixed", "record":
+		return fmt.Sprintf("%s %q", avroType, c.typeName)
+	}
+	return avroType
+}
+
+// longFromBinary decodes a binary encoded long, without allocating.
+func longFromBinary(buf []byte) (int64, []byte, error) {
+	var value uint64
+	var shift uint
+	for offset := 0; offset < len(buf) && shift < 64; offset++ {
+		b := buf[offset]
+		value |= uint64(b&intMask) << shift
+		if b&intFlag == 0 {
+			return int64(value>>1) ^ -int64(value&1), buf[offset+1:], nil
+		}
+		shift += 7
+	}
+	return 0, nil, io.ErrShortBuffer
+}
+
+// bytesFromBinary decodes binary encoded bytes, returning a slice of buf
+// rather than a copy.
+func bytesFromBinary(buf []byte) ([]byte, []byte, error) {
+	size, buf, err := longFromBinary(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size < 0 {
+		return nil, nil, fmt.Errorf("negative size: %d", size)
+	}
+	if size > int64(len(buf)) {
+		return nil, nil, io.ErrShortBuffer
+	}
+	return buf[:size], buf[size:], nil
+}
+
+// blockCountFromBinary decodes the count of items in the next block of a
+// binary encoded array or map, consuming the block size that follows a
+// negative block count.
+func blockCountFromBinary(buf []byte, avroType string) (int64, []byte, error) {
+	blockCount, buf, err := longFromBinary(buf)
+	if err != nil {
+		return 0, nil, fmt.Errorf("cannot decode binary %s block count: %s", avroType, err)
+	}
+	if blockCount < 0 {
+		if blockCount == math.MinInt64 {
+			return 0, nil, fmt.Errorf("cannot decode binary %s with block count: %d", avroType, blockCount)
+		}
+		blockCount = -blockCount
+		if _, buf, err = longFromBinary(buf); err != nil {
+			return 0, nil, fmt.Errorf("cannot decode binary %s block size: %s", avroType, err)
+		}
+	}
+	if blockCount > MaxBlockCount {
+		return 0, nil, fmt.Errorf("cannot decode binary %s when block count exceeds MaxBlockCount: %d > %d", avroType, blockCount, MaxBlockCount)
+	}
+	return blockCount, buf, nil
+}
@@ -0,0 +1,391 @@
+package goavro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// bindingEncoder appends the binary encoding of the Go value v directly to buf,
+// without first converting it to native Go data.
+type bindingEncoder func(buf []byte, v reflect.Value) ([]byte, error)
+
+// encoderBinder binds codecs to Go types, binding each combination of codec
+// and Go type only once, so recursive schemas may be bound to recursive Go
+// types.
+type encoderBinder struct {
+	bound  map[bindingKey]*bindingEncoder
+	failed map[bindingKey]error
+}
+
+// bindEncoder returns a function that encodes a Go value of type t as binary
+// data in accordance with the schema of c, without first converting it to
+// native Go data, or an error when the schema of c cannot be bound to t.
+//
+// Avro types are bound to Go types as described by bindDecoder, with the
+// following differences. A nil pointer may only be encoded as a union having a
+// null member. A union is encoded using its first non-null member that may be
+// bound to the Go type, or to its element type when it is a pointer. A Go value
+// bound to null encodes nothing. Integer values are checked for overflow, and
+// a Go float64 bound to an Avro float is checked for loss of precision. A
+// record field without a corresponding struct field is encoded using its
+// default value, which is encoded once when bound, and it is an error to bind
+// a struct to a record having a field with neither a corresponding struct
+// field nor a default value.
+func bindEncoder(c *Codec, t reflect.Type) (bindingEncoder, error) {
+	b := &encoderBinder{
+		bound:  make(map[bindingKey]*bindingEncoder),
+		failed: make(map[bindingKey]error),
+	}
+	return b.bind(c, t)
+}
+
+func (b *encoderBinder) bind(c *Codec, t reflect.Type) (bindingEncoder, error) {
+	key := bindingKey{c, t}
+	if err, ok := b.failed[key]; ok {
+		return nil, err
+	}
+	if p, ok := b.bound[key]; ok {
+		// NOTE: The encoder may still be being bound when the schema is
+		// recursive, so defer looking it up until it is invoked.
+		return func(buf []byte, v reflect.Value) ([]byte, error) { return (*p)(buf, v) }, nil
+	}
+	p := new(bindingEncoder)
+	b.bound[key] = p
+	e, err := b.bindType(c, t)
+	if err != nil {
+		b.failed[key] = err
+		e = func([]byte, reflect.Value) ([]byte, error) { return nil, err }
+	}
+	*p = e
+	return e, err
+}
+
+func (b *encoderBinder) bindType(c *Codec, t reflect.Type) (bindingEncoder, error) {
+	avroType := c.avroType()
+
+	switch {
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			var datum interface{}
+			if !v.IsNil() {
+				datum = v.Elem().Interface()
+			}
+			return c.binaryFromNative(buf, datum)
+		}, nil
+	case avroType == "null":
+		return func(buf []byte, _ reflect.Value) ([]byte, error) { return buf, nil }, nil
+	case t.Kind() == reflect.Ptr && avroType != "union":
+		elemEncoder, err := b.bind(c, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			if v.IsNil() {
+				return nil, fmt.Errorf("cannot encode binary %s: nil %s", avroType, v.Type())
+			}
+			return elemEncoder(buf, v.Elem())
+		}, nil
+	}
+
+	switch avroType {
+	case "boolean":
+		if t.Kind() == reflect.Bool {
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				if v.Bool() {
+					return append(buf, 1), nil
+				}
+				return append(buf, 0), nil
+			}, nil
+		}
+	case "int", "long":
+		min, max := int64(math.MinInt64), int64(math.MaxInt64)
+		if avroType == "int" {
+			min, max = math.MinInt32, math.MaxInt32
+		}
+		if e := bindIntegerEncoder(t, avroType, min, max); e != nil {
+			return e, nil
+		}
+	case "float", "double":
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if avroType == "double" {
+				return func(buf []byte, v reflect.Value) ([]byte, error) {
+					buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
+					binary.LittleEndian.PutUint64(buf[len(buf)-doubleEncodedLength:], math.Float64bits(v.Float()))
+					return buf, nil
+				}, nil
+			}
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				f := v.Float()
+				value := float32(f)
+				if !math.IsNaN(f) && !math.IsInf(f, 0) && float64(value) != f {
+					return nil, fmt.Errorf("cannot encode binary float: provided Go %s would lose precision: %f", v.Type(), f)
+				}
+				buf = append(buf, 0, 0, 0, 0)
+				binary.LittleEndian.PutUint32(buf[len(buf)-floatEncodedLength:], math.Float32bits(value))
+				return buf, nil
+			}, nil
+		}
+	case "bytes", "string":
+		switch {
+		case t.Kind() == reflect.String:
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				s := v.String()
+				buf = longToBinary(buf, int64(len(s)))
+				return append(buf, s...), nil
+			}, nil
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				someBytes := v.Bytes()
+				buf = longToBinary(buf, int64(len(someBytes)))
+				return append(buf, someBytes...), nil
+			}, nil
+		}
+	case "fixed":
+		size := c.size
+		sizeError := func(count int) error {
+			return fmt.Errorf("cannot encode binary fixed %q: datum size ought to equal schema size: %d != %d", c.typeName, count, size)
+		}
+		switch {
+		case t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 && t.Len() == size:
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				// NOTE: Slicing the array would allocate a slice header.
+				for i := 0; i < size; i++ {
+					buf = append(buf, byte(v.Index(i).Uint()))
+				}
+				return buf, nil
+			}, nil
+		case t.Kind() == reflect.String:
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				s := v.String()
+				if len(s) != size {
+					return nil, sizeError(len(s))
+				}
+				return append(buf, s...), nil
+			}, nil
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				someBytes := v.Bytes()
+				if len(someBytes) != size {
+					return nil, sizeError(len(someBytes))
+				}
+				return append(buf, someBytes...), nil
+			}, nil
+		}
+	case "enum":
+		symbols := c.symbols
+		if t.Kind() == reflect.String {
+			indexFromSymbol := make(map[string]int64, len(symbols))
+			for i, symbol := range symbols {
+				indexFromSymbol[symbol] = int64(i)
+			}
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				index, ok := indexFromSymbol[v.String()]
+				if !ok {
+					return nil, fmt.Errorf("cannot encode binary enum %q: value ought to be member of symbols: %v; %q", c.typeName, symbols, v.String())
+				}
+				return longToBinary(buf, index), nil
+			}, nil
+		}
+		if e := bindIntegerEncoder(t, "enum", 0, int64(len(symbols)-1)); e != nil {
+			return e, nil
+		}
+	case "array":
+		if t.Kind() == reflect.Slice {
+			itemEncoder, err := b.bind(c.items, t.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("cannot bind Avro array items to Go %s: %s", t.Elem(), err)
+			}
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				if count := v.Len(); count > 0 {
+					buf = longToBinary(buf, int64(count))
+					var err error
+					for i := 0; i < count; i++ {
+						if buf, err = itemEncoder(buf, v.Index(i)); err != nil {
+							return nil, fmt.Errorf("cannot encode binary array item %d: %s", i+1, err)
+						}
+					}
+				}
+				return append(buf, 0), nil
+			}, nil
+		}
+	case "map":
+		if t.Kind() == reflect.Map && t.Key().Kind() == reflect.String {
+			valueEncoder, err := b.bind(c.items, t.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("cannot bind Avro map values to Go %s: %s", t.Elem(), err)
+			}
+			return func(buf []byte, v reflect.Value) ([]byte, error) {
+				if count := v.Len(); count > 0 {
+					buf = longToBinary(buf, int64(count))
+					var err error
+					for iter := v.MapRange(); iter.Next(); {
+						key := iter.Key().String()
+						buf = longToBinary(buf, int64(len(key)))
+						buf = append(buf, key...)
+						if buf, err = valueEncoder(buf, iter.Value()); err != nil {
+							return nil, fmt.Errorf("cannot encode binary map value for key %q: %s", key, err)
+						}
+					}
+				}
+				return append(buf, 0), nil
+			}, nil
+		}
+	case "record":
+		if t.Kind() == reflect.Struct {
+			return b.bindRecord(c, t)
+		}
+	case "union":
+		return b.bindUnion(c, t)
+	}
+
+	return nil, fmt.Errorf("cannot bind Avro %s to Go %s", describeBinding(c), t)
+}
+
+// bindRecord binds a record codec to a struct type.
+func (b *encoderBinder) bindRecord(c *Codec, t reflect.Type) (bindingEncoder, error) {
+	type fieldBinding struct {
+		index        int            // index of struct field
+		encoder      bindingEncoder // encodes struct field
+		useDefault   bool           // whether record field has no struct field
+		defaultValue []byte         // encoded default value of record field
+	}
+	fieldBindings := make([]fieldBinding, len(c.fields))
+	for i, field := range c.fields {
+		index := structFieldIndex(t, field.name)
+		if index < 0 {
+			if !field.hasDefault {
+				return nil, fmt.Errorf("cannot bind Avro record %q field %q to Go %s: no struct field and schema does not specify default value", c.typeName, field.name, t)
+			}
+			defaultValue, err := field.codec.binaryFromNative(nil, field.defaultValue)
+			if err != nil {
+				return nil, fmt.Errorf("cannot bind Avro record %q field %q to Go %s: %s", c.typeName, field.name, t, err)
+			}
+			fieldBindings[i] = fieldBinding{useDefault: true, defaultValue: defaultValue}
+			continue
+		}
+		encoder, err := b.bind(field.codec, t.Field(index).Type)
+		if err != nil {
+			return nil, fmt.Errorf("cannot bind Avro record %q field %q to Go %s field %s: %s", c.typeName, field.name, t, t.Field(index).Name, err)
+		}
+		fieldBindings[i] = fieldBinding{index: index, encoder: encoder}
+	}
+
+	return func(buf []byte, v reflect.Value) ([]byte, error) {
+		var err error
+		for i, fb := range fieldBindings {
+			if fb.useDefault {
+				buf = append(buf, fb.defaultValue...)
+				continue
+			}
+			if buf, err = fb.encoder(buf, v.Field(fb.index)); err != nil {
+				return nil, fmt.Errorf("cannot encode binary record %q field %q: value does not match its schema: %s", c.typeName, c.fields[i].name, err)
+			}
+		}
+		return buf, nil
+	}, nil
+}
+
+// bindUnion binds a union codec to a Go type, by binding the first non-null
+// union member that may be bound to it. When the Go type is a pointer, the
+// union members are bound to its element type, and a nil pointer is encoded
+// using the null union member.
+func (b *encoderBinder) bindUnion(c *Codec, t reflect.Type) (bindingEncoder, error) {
+	memberType, indirect := t, false
+	if t.Kind() == reflect.Ptr {
+		memberType, indirect = t.Elem(), true
+	}
+
+	nullIndex := int64(-1)
+	memberIndex := int64(-1)
+	var memberEncoder bindingEncoder
+	var firstErr error
+	for i, member := range c.members {
+		if member.avroType() == "null" {
+			nullIndex = int64(i)
+			continue
+		}
+		if memberEncoder != nil {
+			continue
+		}
+		encoder, err := b.bind(member, memberType)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		memberIndex, memberEncoder = int64(i), encoder
+	}
+	if memberEncoder == nil {
+		if firstErr == nil {
+			return nil, fmt.Errorf("cannot bind Avro %s to Go %s", describeBinding(c), t)
+		}
+		return nil, firstErr
+	}
+
+	return func(buf []byte, v reflect.Value) ([]byte, error) {
+		if indirect {
+			if v.IsNil() {
+				if nullIndex < 0 {
+					return nil, fmt.Errorf("cannot encode binary union: no member schema types support datum: nil %s", v.Type())
+				}
+				return longToBinary(buf, nullIndex), nil
+			}
+			v = v.Elem()
+		}
+		buf = longToBinary(buf, memberIndex)
+		buf, err := memberEncoder(buf, v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode binary union item %d: %s", memberIndex+1, err)
+		}
+		return buf, nil
+	}, nil
+}
+
+// bindIntegerEncoder returns an encoder which encodes an integer or floating
+// point Go value as a long, checking it is an integer between min and max,
+// where min is not greater than 0, or nil when t is not such a type.
+func bindIntegerEncoder(t reflect.Type, avroType string, min, max int64) bindingEncoder {
+	rangeError := func(v reflect.Value) error {
+		return fmt.Errorf("cannot encode binary %s: provided Go %s ought to be integer between %d and %d: %v", avroType, v.Type(), min, max, v)
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			value := v.Int()
+			if value < min || value > max {
+				return nil, rangeError(v)
+			}
+			return longToBinary(buf, value), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			value := v.Uint()
+			if value > uint64(max) {
+				return nil, rangeError(v)
+			}
+			return longToBinary(buf, int64(value)), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			f := v.Float()
+			value := int64(f)
+			if float64(value) != f || value < min || value > max {
+				return nil, rangeError(v)
+			}
+			return longToBinary(buf, value), nil
+		}
+	}
+	return nil
+}
+
+// longToBinary appends the binary encoding of a long, which is also the binary
+// encoding of an int when value is in range, without allocating.
+func longToBinary(buf []byte, value int64) []byte {
+	encoded := (uint64(value) << 1) ^ uint64(value>>longDownShift)
+	buf, _ = integerBinaryEncoder(buf, encoded) // never fails
+	return buf
+}
@@ -1,6 +1,7 @@
 package goavro
 
 import (
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -356,3 +357,67 @@ var (
 	sliceTab            = []byte("\\t")
 	sliceUnicode        = []byte("\\u")
 )
+
+////////////////////////////////////////
+// Standard JSON Decode
+////////////////////////////////////////
+
+// bytesNativeFromJSON returns a function that decodes a standard JSON string to
+// a byte slice, using the specified encoding, either JSONBytesBase64Label or
+// JSONBytesHexLabel.
+func bytesNativeFromJSON(encoding string) func([]byte) (interface{}, []byte, error) {
+	return func(buf []byte) (interface{}, []byte, error) {
+		value, newBuf, err := stringNativeFromTextual(buf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot decode JSON bytes: %s", err)
+		}
+		var decoded []byte
+		if encoding == JSONBytesHexLabel {
+			decoded, err = hex.DecodeString(value.(string))
+		} else {
+			decoded, err = base64.StdEncoding.DecodeString(value.(string))
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot decode JSON bytes: invalid %s: %s", encoding, err)
+		}
+		return decoded, newBuf, nil
+	}
+}
+
+////////////////////////////////////////
+// Standard JSON Encode
+////////////////////////////////////////
+
+// bytesJSONFromNative returns a function that encodes a byte slice as a
+// standard JSON string, using the specified encoding, either
+// JSONBytesBase64Label or JSONBytesHexLabel.
+func bytesJSONFromNative(encoding string) func([]byte, interface{}) ([]byte, error) {
+	return func(buf []byte, datum interface{}) ([]byte, error) {
+		someBytes, ok := datum.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode JSON bytes: expected: []byte; received: %T", datum)
+		}
+		return appendEncodedBytes(buf, someBytes, encoding), nil
+	}
+}
+
+// appendEncodedBytes appends someBytes to buf as a double quoted string, using
+// the specified encoding. Neither base64 nor hexidecimal encoding emit any
+// characters that must be escaped in JSON.
+func appendEncodedBytes(buf, someBytes []byte, encoding string) []byte {
+	var size int
+	if encoding == JSONBytesHexLabel {
+		size = hex.EncodedLen(len(someBytes))
+	} else {
+		size = base64.StdEncoding.EncodedLen(len(someBytes))
+	}
+	buf = append(buf, '"')
+	offset := len(buf)
+	buf = append(buf, make([]byte, size)...) // grow to make room for encoded bytes
+	if encoding == JSONBytesHexLabel {
+		hex.Encode(buf[offset:], someBytes)
+	} else {
+		base64.StdEncoding.Encode(buf[offset:], someBytes)
+	}
+	return append(buf, '"')
+}
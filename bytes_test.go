@@ -2,6 +2,8 @@ package goavro_test
 
 import (
 	"testing"
+
+	"github.com/karrick/goavro"
 )
 
 func TestSchemaPrimitiveCodecBytes(t *testing.T) {
@@ -107,3 +109,14 @@ func TestPrimitiveStringText(t *testing.T) {
 	testTextDecodeFail(t, "string", []byte("\"\\uD83D\\uDE\""), "surrogate pair")
 	testTextDecodeFail(t, "string", []byte("\"\\uD83D\\uDE0\""), "invalid byte")
 }
+
+func TestBytesJSONCodec(t *testing.T) {
+	testJSONCodecPass(t, "bytes", goavro.CodecConfig{}, []byte(""), []byte(`""`))
+	testJSONCodecPass(t, "bytes", goavro.CodecConfig{}, []byte("hello"), []byte(`"aGVsbG8="`))
+	testJSONCodecPass(t, "bytes", goavro.CodecConfig{JSONBytesEncoding: goavro.JSONBytesBase64Label}, []byte{0xff, 0xfe}, []byte(`"//4="`))
+	testJSONCodecPass(t, "bytes", goavro.CodecConfig{JSONBytesEncoding: goavro.JSONBytesHexLabel}, []byte{0xff, 0x00, 0x1a}, []byte(`"ff001a"`))
+	testJSONDecodeFail(t, "bytes", []byte(`"not base64!"`), "invalid base64")
+
+	_, err := goavro.NewCodecWithConfig("bytes", goavro.CodecConfig{JSONBytesEncoding: "base32"})
+	ensureError(t, err, "unrecognized JSON bytes encoding")
+}
@@ -1,9 +1,11 @@
 package goavro
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math"
+	"sync"
 )
 
 var (
@@ -30,6 +32,94 @@ var (
 	MaxBlockSize = int64(math.MaxInt32)
 )
 
+// CodecConfig is used to specify creation parameters for a Codec created by
+// NewCodecWithConfig.
+type CodecConfig struct {
+	// SortMapKeys specifies whether the textual encoding of Avro maps emits
+	// its keys in sorted order, (optional). By default, map keys are emitted
+	// in whatever order the Go runtime iterates over them, which changes from
+	// one invocation to the next. Record fields are always emitted in the
+	// order they are defined in the schema.
+	SortMapKeys bool
+
+	// Indent specifies the string used to indent each nested level of the
+	// textual encoding, (optional). When empty, the textual encoding is
+	// emitted in its compact form, without any insignificant whitespace.
+	// Otherwise each JSON element begins on a new line, prefixed by one copy
+	// of Indent for each level of nesting.
+	Indent string
+
+	// JSONBytesEncoding specifies how JSONFromNative and NativeFromJSON
+	// represent Avro bytes and fixed values as JSON strings, (optional). Either
+	// JSONBytesBase64Label or JSONBytesHexLabel. If omitted, defaults to
+	// JSONBytesBase64Label. It has no effect on the Avro JSON representation
+	// used by TextualFromNative and NativeFromTextual.
+	JSONBytesEncoding string
+
+	// InferUnionBranch specifies whether the encoders accept a bare Go value
+	// for an Avro union, selecting the union member from the Go type of the
+	// value, (optional). When false, non-nil union values must be wrapped in a
+	// map with a single key equal to the member type name, as created by the
+	// Union function. When true, wrapped values are still accepted, and a
+	// map[string]interface{} with a single key equal to a member type name is
+	// always taken to be a wrapped value, even when another member could
+	// encode the map itself. Any other value selects the union member whose
+	// type matches the Go type of the value exactly, for instance int32
+	// selects int, int64 and int select long, float64 selects double, and
+	// []byte selects bytes. If there is no exact match, the union member that
+	// is able to encode the value is selected, and when more than one such
+	// member is numeric, the first one in schema order, per Avro numeric type
+	// promotion. It is an error when no union member or more than one
+	// non-numeric union member is able to encode the value.
+	InferUnionBranch bool
+
+	// UnionDecoding specifies how the decoders return non-nil Avro union
+	// values, (optional). Either UnionDecodingMapLabel, UnionDecodingBareLabel,
+	// or UnionDecodingValueLabel. If omitted, defaults to
+	// UnionDecodingMapLabel. It applies to every union in the schema,
+	// including those nested inside records, arrays, and maps. Null union
+	// values are always decoded as nil. NOTE: Bare values may only be encoded
+	// when InferUnionBranch is also set.
+	UnionDecoding string
+
+	// UnionShortNames specifies whether union members that are named types
+	// may be identified by their short name, without the namespace, as well as
+	// by their full name, (optional). This applies to the map keys provided to
+	// the encoders, and to the type name keys in textual Avro data provided to
+	// NativeFromTextual. A short name is only accepted when no other member of
+	// the same union has the same short name. Decoders and TextualFromNative
+	// always emit the full name.
+	UnionShortNames bool
+
+	// standardJSON is set when building the codec used for standard JSON
+	// rather than Avro JSON.
+	standardJSON bool
+}
+
+const (
+	// JSONBytesBase64Label is used when JSONFromNative represents bytes and
+	// fixed values using standard base64 encoding, with padding.
+	JSONBytesBase64Label = "base64"
+
+	// JSONBytesHexLabel is used when JSONFromNative represents bytes and fixed
+	// values using lower case hexidecimal encoding.
+	JSONBytesHexLabel = "hex"
+
+	// UnionDecodingMapLabel is used when the decoders return non-nil union
+	// values wrapped in a map with a single key equal to the full name of the
+	// union member type, as created by the Union function.
+	UnionDecodingMapLabel = "map"
+
+	// UnionDecodingBareLabel is used when the decoders return non-nil union
+	// values without any wrapper.
+	UnionDecodingBareLabel = "bare"
+
+	// UnionDecodingValueLabel is used when the decoders return non-nil union
+	// values as a UnionValue, which includes the full name of the union member
+	// type.
+	UnionDecodingValueLabel = "value"
+)
+
 // Codec supports decoding binary and text Avro data to Go native data types,
 // and conversely encoding Go native data types to binary or text Avro data. A
 // Codec is created as a stateless structure that can be safely used in multiple
@@ -38,15 +128,29 @@ type Codec struct {
 	typeName        *name
 	schema          string
 	canonicalSchema string
+	config          CodecConfig
+
+	jsonOnce  sync.Once
+	jsonCodec *Codec // standard JSON flavor of this codec, created on first use
+	jsonErr   error
 
 	nativeFromTextual func([]byte) (interface{}, []byte, error)
 	binaryFromNative  func([]byte, interface{}) ([]byte, error)
 	nativeFromBinary  func([]byte) (interface{}, []byte, error)
 	textualFromNative func([]byte, interface{}) ([]byte, error)
+
+	// The following describe complex types, so Go types may be bound to the
+	// codec. See avroType.
+	items   *Codec        // array items or map values
+	members []*Codec      // union members
+	fields  []recordField // record fields
+	symbols []string      // enum symbols
+	size    int           // fixed size
+	aliases []string      // named type aliases, as full names
 }
 
-func newSymbolTable() map[string]*Codec {
-	return map[string]*Codec{
+func newSymbolTable(config *CodecConfig) map[string]*Codec {
+	st := map[string]*Codec{
 		"boolean": &Codec{
 			typeName:          &name{"boolean", nullNamespace},
 			binaryFromNative:  booleanBinaryFromNative,
@@ -105,6 +209,13 @@ func newSymbolTable() map[string]*Codec {
 			textualFromNative: stringTextualFromNative,
 		},
 	}
+	if config.standardJSON {
+		// NOTE: Only the bytes primitive type has a different representation
+		// in standard JSON than in Avro JSON.
+		st["bytes"].nativeFromTextual = bytesNativeFromJSON(config.JSONBytesEncoding)
+		st["bytes"].textualFromNative = bytesJSONFromNative(config.JSONBytesEncoding)
+	}
+	return st
 }
 
 // NewCodec returns a Codec used to translate between a byte slice of either
@@ -119,8 +230,10 @@ func newSymbolTable() map[string]*Codec {
 // there is no practical limit to how many `Codec`s may be created and
 // used in a program. Internally a `Codec` is merely a named tuple of
 // four function pointers, and maintains no runtime state that is mutated
-// after instantiation. In other words, `Codec`s may be safely used by
-// many go routines simultaneously, as your program requires.
+// after instantiation, other than the standard JSON flavor of the `Codec`,
+// which is created exactly once the first time it is needed. In other words,
+// `Codec`s may be safely used by many go routines simultaneously, as your
+// program requires.
 //
 //     codec, err := goavro.NewCodec(`
 //         {
@@ -134,14 +247,50 @@ func newSymbolTable() map[string]*Codec {
 //             fmt.Println(err)
 //     }
 func NewCodec(schemaSpecification string) (*Codec, error) {
+	return NewCodecWithConfig(schemaSpecification, CodecConfig{})
+}
+
+// NewCodecWithConfig returns a Codec used to translate between a byte slice of
+// either binary or textual Avro data and native Go data, using the provided
+// configuration parameters to alter the behavior of the returned Codec. The
+// zero value of CodecConfig results in a Codec that behaves exactly like one
+// returned by NewCodec.
+//
+//     codec, err := goavro.NewCodecWithConfig(`{"type":"map","values":"int"}`, goavro.CodecConfig{
+//         SortMapKeys: true,
+//         Indent:      "  ",
+//     })
+//     if err != nil {
+//             fmt.Println(err)
+//     }
+func NewCodecWithConfig(schemaSpecification string, config CodecConfig) (*Codec, error) {
+	switch config.JSONBytesEncoding {
+	case "":
+		config.JSONBytesEncoding = JSONBytesBase64Label
+	case JSONBytesBase64Label, JSONBytesHexLabel:
+		// no-op
+	default:
+		return nil, fmt.Errorf("cannot create Codec using unrecognized JSON bytes encoding: %q", config.JSONBytesEncoding)
+	}
+
+	switch config.UnionDecoding {
+	case "":
+		config.UnionDecoding = UnionDecodingMapLabel
+	case UnionDecodingMapLabel, UnionDecodingBareLabel, UnionDecodingValueLabel:
+		// no-op
+	default:
+		return nil, fmt.Errorf("cannot create Codec using unrecognized union decoding: %q", config.UnionDecoding)
+	}
+
 	// bootstrap a symbol table with primitive type codecs for the new codec
-	st := newSymbolTable()
+	st := newSymbolTable(&config)
 
 	// NOTE: Some clients might give us unadorned primitive type name for the
 	// schema, e.g., "long". While it is not valid JSON, it is a valid schema.
 	// Provide special handling for primitive type names.
 	if c, ok := st[schemaSpecification]; ok {
 		c.schema = schemaSpecification
+		c.config = config
 		return c, nil
 	}
 
@@ -152,8 +301,10 @@ func NewCodec(schemaSpecification string) (*Codec, error) {
 		return nil, fmt.Errorf("cannot unmarshal schema JSON: %s", err)
 	}
 
-	c, err := buildCodec(st, nullNamespace, schema)
+	c, err := buildCodec(st, &config, nullNamespace, schema)
 	if err == nil {
+		c.config = config
+
 		// compact schema and save it
 		compact, err := json.Marshal(schema)
 		if err != nil {
@@ -336,9 +487,84 @@ func (c *Codec) TextualFromNative(buf []byte, datum interface{}) ([]byte, error)
 	if err != nil {
 		return buf, err // if error, return original byte slice
 	}
+	if c.config.Indent != "" {
+		if newBuf, err = indentAppended(buf, newBuf, c.config.Indent); err != nil {
+			return buf, err
+		}
+	}
 	return newBuf, nil
 }
 
+// JSONFromNative converts Go native data types to standard JSON text, rather
+// than Avro JSON, in accordance with the Avro schema supplied when creating the
+// Codec. It accepts the same native datum values as TextualFromNative, but
+// emits JSON that is more convenient for consumers that are not Avro aware:
+// union values are emitted without being wrapped in an object keyed by their
+// type name, and bytes and fixed values are emitted as strings using the
+// encoding specified by the JSONBytesEncoding configuration parameter. On
+// success, it returns a new byte slice with the encoded bytes appended, and a
+// nil error value. On error, it returns the original byte slice, and the error
+// message.
+//
+//     codec, err := goavro.NewCodec(`["null","string","bytes"]`)
+//     if err != nil {
+//         fmt.Println(err)
+//     }
+//     buf, err := codec.JSONFromNative(nil, goavro.Union("bytes", []byte("hello")))
+//     if err != nil {
+//         fmt.Println(err)
+//     }
+//     fmt.Println(string(buf))
+//     // Output: "aGVsbG8="
+func (c *Codec) JSONFromNative(buf []byte, datum interface{}) ([]byte, error) {
+	jc, err := c.standardJSONCodec()
+	if err != nil {
+		return buf, err
+	}
+	return jc.TextualFromNative(buf, datum)
+}
+
+// NativeFromJSON converts standard JSON text, as emitted by JSONFromNative, to
+// Go native data types in accordance with the Avro schema supplied when
+// creating the Codec. It returns the same native datum values as
+// NativeFromTextual. Because standard JSON does not identify which member of a
+// union a value belongs to, the union member is inferred from the shape of the
+// JSON value: the first member of the union, in schema order, that is able to
+// decode the JSON value is selected. On success, it returns the decoded datum,
+// along with a new byte slice with the decoded bytes consumed, and a nil error
+// value. On error, it returns nil for the datum value, the original byte slice,
+// and the error message.
+func (c *Codec) NativeFromJSON(buf []byte) (interface{}, []byte, error) {
+	jc, err := c.standardJSONCodec()
+	if err != nil {
+		return nil, buf, err
+	}
+	return jc.NativeFromTextual(buf)
+}
+
+// standardJSONCodec returns the standard JSON flavor of this Codec, creating it
+// the first time it is needed.
+func (c *Codec) standardJSONCodec() (*Codec, error) {
+	c.jsonOnce.Do(func() {
+		config := c.config
+		config.standardJSON = true
+		if c.jsonCodec, c.jsonErr = NewCodecWithConfig(c.schema, config); c.jsonErr != nil {
+			c.jsonErr = fmt.Errorf("cannot create standard JSON codec: %s", c.jsonErr)
+		}
+	})
+	return c.jsonCodec, c.jsonErr
+}
+
+// indentAppended indents the bytes that were appended to buf to create newBuf,
+// leaving whatever bytes the client provided in buf untouched.
+func indentAppended(buf, newBuf []byte, indent string) ([]byte, error) {
+	bb := bytes.NewBuffer(make([]byte, 0, 2*(len(newBuf)-len(buf))))
+	if err := json.Indent(bb, newBuf[len(buf):], "", indent); err != nil {
+		return nil, fmt.Errorf("cannot indent textual encoding: %s", err)
+	}
+	return append(newBuf[:len(buf)], bb.Bytes()...), nil
+}
+
 // Schema returns the compact schema used to create the Codec.
 //
 //     func ExampleCodecSchema() {
@@ -359,23 +585,38 @@ func (c *Codec) CanonicalSchema() string {
 	return c.canonicalSchema
 }
 
+// avroType returns the Avro type of the codec, which for primitive types,
+// arrays, maps, and unions is also its type name.
+func (c *Codec) avroType() string {
+	switch {
+	case c.fields != nil:
+		return "record"
+	case c.symbols != nil:
+		return "enum"
+	case c.size > 0:
+		return "fixed"
+	default:
+		return c.typeName.fullName
+	}
+}
+
 // convert a schema data structure to a codec, prefixing with specified
 // namespace
-func buildCodec(st map[string]*Codec, enclosingNamespace string, schema interface{}) (*Codec, error) {
+func buildCodec(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, schema interface{}) (*Codec, error) {
 	switch schemaType := schema.(type) {
 	case map[string]interface{}:
-		return buildCodecForTypeDescribedByMap(st, enclosingNamespace, schemaType)
+		return buildCodecForTypeDescribedByMap(st, config, enclosingNamespace, schemaType)
 	case string:
-		return buildCodecForTypeDescribedByString(st, enclosingNamespace, schemaType, nil)
+		return buildCodecForTypeDescribedByString(st, config, enclosingNamespace, schemaType, nil)
 	case []interface{}:
-		return buildCodecForTypeDescribedBySlice(st, enclosingNamespace, schemaType)
+		return buildCodecForTypeDescribedBySlice(st, config, enclosingNamespace, schemaType)
 	default:
 		return nil, fmt.Errorf("unknown schema type: %T", schema)
 	}
 }
 
 // Reach into the map, grabbing its "type". Use that to create the codec.
-func buildCodecForTypeDescribedByMap(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func buildCodecForTypeDescribedByMap(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
 	t, ok := schemaMap["type"]
 	if !ok {
 		return nil, fmt.Errorf("missing type: %v", schemaMap)
@@ -389,17 +630,17 @@ func buildCodecForTypeDescribedByMap(st map[string]*Codec, enclosingNamespace st
 		// EXAMPLE: "type":"int"
 		// EXAMPLE: "type":"record"
 		// EXAMPLE: "type":"somePreviouslyDefinedCustomTypeString"
-		return buildCodecForTypeDescribedByString(st, enclosingNamespace, v, schemaMap)
+		return buildCodecForTypeDescribedByString(st, config, enclosingNamespace, v, schemaMap)
 	case map[string]interface{}:
-		return buildCodecForTypeDescribedByMap(st, enclosingNamespace, v)
+		return buildCodecForTypeDescribedByMap(st, config, enclosingNamespace, v)
 	case []interface{}:
-		return buildCodecForTypeDescribedBySlice(st, enclosingNamespace, v)
+		return buildCodecForTypeDescribedBySlice(st, config, enclosingNamespace, v)
 	default:
 		return nil, fmt.Errorf("type ought to be either string, map[string]interface{}, or []interface{}; received: %T", t)
 	}
 }
 
-func buildCodecForTypeDescribedByString(st map[string]*Codec, enclosingNamespace string, typeName string, schemaMap map[string]interface{}) (*Codec, error) {
+func buildCodecForTypeDescribedByString(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, typeName string, schemaMap map[string]interface{}) (*Codec, error) {
 	// NOTE: When codec already exists, return it. This includes both primitive
 	// type codecs added in NewCodec, and user-defined types, added while
 	// building the codec.
@@ -415,15 +656,15 @@ func buildCodecForTypeDescribedByString(st map[string]*Codec, enclosingNamespace
 	// There are only a small handful of complex Avro data types.
 	switch typeName {
 	case "array":
-		return makeArrayCodec(st, enclosingNamespace, schemaMap)
+		return makeArrayCodec(st, config, enclosingNamespace, schemaMap)
 	case "enum":
-		return makeEnumCodec(st, enclosingNamespace, schemaMap)
+		return makeEnumCodec(st, config, enclosingNamespace, schemaMap)
 	case "fixed":
-		return makeFixedCodec(st, enclosingNamespace, schemaMap)
+		return makeFixedCodec(st, config, enclosingNamespace, schemaMap)
 	case "map":
-		return makeMapCodec(st, enclosingNamespace, schemaMap)
+		return makeMapCodec(st, config, enclosingNamespace, schemaMap)
 	case "record":
-		return makeRecordCodec(st, enclosingNamespace, schemaMap)
+		return makeRecordCodec(st, config, enclosingNamespace, schemaMap)
 	default:
 		return nil, fmt.Errorf("unknown type name: %q", typeName)
 	}
@@ -437,6 +678,13 @@ func registerNewCodec(st map[string]*Codec, schemaMap map[string]interface{}, en
 		return nil, err
 	}
 	c := &Codec{typeName: n}
+	for _, alias := range aliasesFromSchemaMap(schemaMap) {
+		// NOTE: Aliases of named types are relative to the namespace of the
+		// type.
+		if an, err := newName(alias, nullNamespace, n.namespace); err == nil {
+			c.aliases = append(c.aliases, an.fullName)
+		}
+	}
 	st[n.fullName] = c
 	return c, nil
 }
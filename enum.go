@@ -7,7 +7,7 @@ import (
 
 // enum does not have child objects, therefore whatever namespace it defines is
 // just to store its name in the symbol table.
-func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeEnumCodec(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
 	c, err := registerNewCodec(st, schemaMap, enclosingNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("Enum ought to have valid name: %s", err)
@@ -33,6 +33,7 @@ func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap ma
 		}
 		symbols[i] = symbol
 	}
+	c.symbols = symbols
 
 	c.nativeFromBinary = func(buf []byte) (interface{}, []byte, error) {
 		var value interface{}
@@ -27,7 +27,7 @@ func usage(err error) {
 	}
 	base := filepath.Base(executable)
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", base)
-	fmt.Fprintf(os.Stderr, "\t%s [-v] [-summary] [-bc N] [-compression null|deflate|snappy] [-schema new-schema.avsc] source.avro destination.avro\n", base)
+	fmt.Fprintf(os.Stderr, "\t%s [-v] [-summary] [-bc N] [-compression null|deflate|snappy|zstandard|bzip2|xz] [-schema new-schema.avsc] source.avro destination.avro\n", base)
 	fmt.Fprintf(os.Stderr, "\tWhen source.avro pathname is hyphen, %s will read from its standard input.\n", base)
 	fmt.Fprintf(os.Stderr, "\tWhen destination.avro pathname is hyphen, %s will write to its standard output.\n", base)
 	flag.PrintDefaults()
@@ -41,7 +41,7 @@ var (
 )
 
 func init() {
-	compressionName = flag.String("compression", "", "compression codec ('null', 'deflate', 'snappy'; default: use source compression)")
+	compressionName = flag.String("compression", "", "compression codec ('null', 'deflate', 'snappy', 'zstandard', 'bzip2', 'xz'; default: use source compression)")
 	blockCount = flag.Int("bc", 0, "max count of items in each block (default: use source block boundaries)")
 	schemaPathname = flag.String("schema", "", "pathname to new schema (default: use source schema)")
 	summary = flag.Bool("summary", false, "print summary information to stderr")
@@ -163,11 +163,48 @@ func main() {
 		bail(err)
 	}
 
-	if err := transcode(ocfr, ocfw); err != nil {
+	// NOTE: When neither schema nor block count changes, blocks are copied
+	// without decoding their data items, and are only recompressed when the
+	// compression algorithm changes.
+	if *schemaPathname == "" && *blockCount == 0 {
+		err = copyBlocks(ocfr, ocfw)
+	} else {
+		err = transcode(ocfr, ocfw)
+	}
+	if err != nil {
 		bail(err)
 	}
 }
 
+func copyBlocks(from *goavro.OCFReader, to *goavro.OCFWriter) error {
+	var blocksCopied, itemsCopied int64
+
+	for {
+		block, err := from.NextBlock()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "copying block with %d items\n", block.Count)
+		}
+		if err = to.AppendRawBlock(block); err != nil {
+			return err
+		}
+		blocksCopied++
+		itemsCopied += block.Count
+	}
+
+	if *summary {
+		fmt.Fprintf(os.Stderr, "copied %d items\n", itemsCopied)
+		fmt.Fprintf(os.Stderr, "copied %d blocks\n", blocksCopied)
+	}
+
+	return nil
+}
+
 func transcode(from *goavro.OCFReader, to *goavro.OCFWriter) error {
 	var blocksRead, blocksWritten, itemsRead int
 
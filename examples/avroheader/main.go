@@ -6,13 +6,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/karrick/goavro"
 )
 
 var (
-	showCount  = flag.Bool("count", false, "show count of data items")
-	showSchema = flag.Bool("schema", false, "show data schema")
+	showCount    = flag.Bool("count", false, "show count of data items")
+	showMetadata = flag.Bool("metadata", false, "show application specific metadata")
+	showSchema   = flag.Bool("schema", false, "show data schema")
 )
 
 func usage() {
@@ -22,7 +24,7 @@ func usage() {
 	}
 	base := filepath.Base(executable)
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", base)
-	fmt.Fprintf(os.Stderr, "\t%s [-count] [-schema] [file1.avro...]\n", base)
+	fmt.Fprintf(os.Stderr, "\t%s [-count] [-metadata] [-schema] [file1.avro...]\n", base)
 	fmt.Fprintf(os.Stderr, "\tAs a special case, when there are no filename arguments, %s will read\n", base)
 	fmt.Fprintf(os.Stderr, "\tfrom its standard input.\n")
 	flag.PrintDefaults()
@@ -74,6 +76,18 @@ func headerFromReader(ior io.Reader, prefix string) error {
 
 	fmt.Printf("%sCompression Algorithm (avro.codec): %q\n", prefix, ocfr.CompressionName())
 
+	if *showMetadata {
+		metadata := ocfr.MetaData()
+		keys := make([]string, 0, len(metadata))
+		for key := range metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("%sMetadata %q: %q\n", prefix, key, metadata[key])
+		}
+	}
+
 	if *showSchema {
 		fmt.Printf("%sSchema (avro.schema):\n%s\n", prefix, ocfr.Codec().Schema())
 	}
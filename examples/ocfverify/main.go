@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/karrick/goavro"
+)
+
+var (
+	quiet   = flag.Bool("q", false, "print only problems")
+	verbose = flag.Bool("v", false, "print statistics for each block")
+)
+
+func usage() {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	base := filepath.Base(executable)
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", base)
+	fmt.Fprintf(os.Stderr, "\t%s [-q | -v] [file1.avro...]\n", base)
+	fmt.Fprintf(os.Stderr, "\tVerifies the integrity of each OCF, reporting every problem found, and\n")
+	fmt.Fprintf(os.Stderr, "\texits with status 1 when any OCF has a problem.\n")
+	fmt.Fprintf(os.Stderr, "\tAs a special case, when there are no filename arguments, %s will read\n", base)
+	fmt.Fprintf(os.Stderr, "\tfrom its standard input.\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	var invalid bool
+
+	if len(args) == 0 {
+		stat, err := os.Stdin.Stat()
+		if err != nil {
+			bail(err)
+		}
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			usage()
+		}
+		if !verify(os.Stdin, "") {
+			invalid = true
+		}
+	}
+
+	for _, arg := range args {
+		fh, err := os.Open(arg)
+		if err != nil {
+			bail(err)
+		}
+		if !verify(fh, arg+": ") {
+			invalid = true
+		}
+		if err := fh.Close(); err != nil {
+			bail(err)
+		}
+	}
+
+	if invalid {
+		os.Exit(1)
+	}
+}
+
+// verify prints the report of the OCF read from ior, and returns true when it
+// has no problems.
+func verify(ior io.Reader, prefix string) bool {
+	report, err := goavro.VerifyOCF(bufio.NewReader(ior))
+	if err != nil {
+		fmt.Printf("%s%s\n", prefix, err)
+		return false
+	}
+
+	if *verbose {
+		fmt.Printf("%sCompression Algorithm (avro.codec): %q\n", prefix, report.CompressionName)
+		for i, block := range report.Blocks {
+			fmt.Printf("%sblock %d: offset: %d; length: %d; count: %d; compressed: %d; uncompressed: %d; decoded: %d\n",
+				prefix, i, block.Offset, block.Length, block.Count, block.CompressedSize, block.UncompressedSize, block.Items)
+		}
+	}
+
+	for _, problem := range report.Problems {
+		fmt.Printf("%sproblem: offset: %d; length: %d; items lost: %d; %s\n", prefix, problem.Offset, problem.Length, problem.Items, problem.Err)
+	}
+
+	if !*quiet || len(report.Problems) > 0 {
+		fmt.Printf("%sblocks: %d; items: %d; problems: %d\n", prefix, len(report.Blocks), report.Items, len(report.Problems))
+	}
+
+	return len(report.Problems) == 0
+}
+
+func bail(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	os.Exit(1)
+}
@@ -81,7 +81,7 @@ func main() {
 			}
 			bail(err)
 		}
-		if err = ocfw.Append([]interface{}{datum}); err != nil {
+		if err = ocfw.Write(datum); err != nil {
 			bail(err)
 		}
 	}
@@ -89,4 +89,7 @@ func main() {
 	if err != nil {
 		bail(err)
 	}
+	if err = ocfw.Close(); err != nil {
+		bail(err)
+	}
 }
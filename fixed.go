@@ -6,7 +6,7 @@ import (
 
 // Fixed does not have child objects, therefore whatever namespace it defines is
 // just to store its name in the symbol table.
-func makeFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeFixedCodec(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
 	c, err := registerNewCodec(st, schemaMap, enclosingNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("Fixed ought to have valid name: %s", err)
@@ -21,6 +21,7 @@ func makeFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap m
 		return nil, fmt.Errorf("Fixed %q size ought to be number greater than zero: %v", c.typeName, s1)
 	}
 	size := uint(s2)
+	c.size = int(size)
 
 	c.nativeFromBinary = func(buf []byte) (interface{}, []byte, error) {
 		if buflen := uint(len(buf)); size > buflen {
@@ -68,5 +69,31 @@ func makeFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap m
 		return bytesTextualFromNative(buf, someBytes)
 	}
 
+	if config.standardJSON {
+		nativeFromJSON := bytesNativeFromJSON(config.JSONBytesEncoding)
+
+		c.nativeFromTextual = func(buf []byte) (interface{}, []byte, error) {
+			datum, newBuf, err := nativeFromJSON(buf)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot decode JSON fixed %q: %s", c.typeName, err)
+			}
+			if count := uint(len(datum.([]byte))); count != size {
+				return nil, nil, fmt.Errorf("cannot decode JSON fixed %q: datum size ought to equal schema size: %d != %d", c.typeName, count, size)
+			}
+			return datum, newBuf, nil
+		}
+
+		c.textualFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+			someBytes, ok := datum.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("cannot encode JSON fixed %q: expected []byte; received: %T", c.typeName, datum)
+			}
+			if count := uint(len(someBytes)); count != size {
+				return nil, fmt.Errorf("cannot encode JSON fixed %q: datum size ought to equal schema size: %d != %d", c.typeName, count, size)
+			}
+			return appendEncodedBytes(buf, someBytes, config.JSONBytesEncoding), nil
+		}
+	}
+
 	return c, nil
 }
@@ -67,3 +67,10 @@ func TestFixedTextCodec(t *testing.T) {
 	testTextEncodeFail(t, schema, []byte{1, 2, 3, 4, 5}, "datum size ought to equal schema size")
 	testTextEncodePass(t, schema, []byte{1, 2, 3, 4}, []byte(`"\u0001\u0002\u0003\u0004"`))
 }
+
+func TestFixedJSONCodec(t *testing.T) {
+	schema := `{"type":"fixed","name":"f1","size":4}`
+	testJSONCodecPass(t, schema, goavro.CodecConfig{}, []byte{1, 2, 3, 4}, []byte(`"AQIDBA=="`))
+	testJSONCodecPass(t, schema, goavro.CodecConfig{JSONBytesEncoding: goavro.JSONBytesHexLabel}, []byte{1, 2, 3, 4}, []byte(`"01020304"`))
+	testJSONDecodeFail(t, schema, []byte(`"AQID"`), "datum size ought to equal schema size")
+}
@@ -6,21 +6,23 @@ import (
 	"io"
 	"math"
 	"reflect"
+	"sort"
 )
 
-func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeMapCodec(st map[string]*Codec, config *CodecConfig, namespace string, schemaMap map[string]interface{}) (*Codec, error) {
 	// map type must have values
 	valueSchema, ok := schemaMap["values"]
 	if !ok {
 		return nil, errors.New("Map ought to have values key")
 	}
-	valueCodec, err := buildCodec(st, namespace, valueSchema)
+	valueCodec, err := buildCodec(st, config, namespace, valueSchema)
 	if err != nil {
 		return nil, fmt.Errorf("Map values ought to be valid Avro type: %s", err)
 	}
 
 	return &Codec{
 		typeName: &name{"map", nullNamespace},
+		items:    valueCodec,
 		nativeFromBinary: func(buf []byte) (interface{}, []byte, error) {
 			var err error
 			var value interface{}
@@ -135,7 +137,7 @@ func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]i
 			return genericMapTextDecoder(buf, valueCodec, nil) // codecFromKey == nil
 		},
 		textualFromNative: func(buf []byte, datum interface{}) ([]byte, error) {
-			return genericMapTextEncoder(buf, datum, valueCodec, nil)
+			return genericMapTextEncoder(buf, datum, valueCodec, nil, config.SortMapKeys)
 		},
 	}, nil
 }
@@ -224,19 +226,28 @@ func genericMapTextDecoder(buf []byte, defaultCodec *Codec, codecFromKey map[str
 // defaultCodec if provided. If defaultCodec is nil, this function returns an
 // error if it encounters a map key that is not present in codecFromKey. If
 // codecFromKey is nil, every map value will be encoded using defaultCodec, if
-// possible.
-func genericMapTextEncoder(buf []byte, datum interface{}, defaultCodec *Codec, codecFromKey map[string]*Codec) ([]byte, error) {
+// possible. When sortKeys is true, map keys are encoded in sorted order.
+func genericMapTextEncoder(buf []byte, datum interface{}, defaultCodec *Codec, codecFromKey map[string]*Codec, sortKeys bool) ([]byte, error) {
 	mapValues, err := convertMap(datum)
 	if err != nil {
 		return nil, fmt.Errorf("cannot encode textual map: %s", err)
 	}
 
+	keys := make([]string, 0, len(mapValues))
+	for key := range mapValues {
+		keys = append(keys, key)
+	}
+	if sortKeys {
+		sort.Strings(keys)
+	}
+
 	var atLeastOne bool
 
 	buf = append(buf, '{')
 
-	for key, value := range mapValues {
+	for _, key := range keys {
 		atLeastOne = true
+		value := mapValues[key]
 
 		// Find a codec for the key
 		fieldCodec := codecFromKey[key]
@@ -142,6 +142,39 @@ func TestMapTextualReceiveSliceInt(t *testing.T) {
 	testTextEncodeFail(t, `{"type":"map","values":"int"}`, map[int]int{42: 13}, "cannot create map[string]interface{}")
 }
 
+func TestMapTextualSortMapKeys(t *testing.T) {
+	codec, err := goavro.NewCodecWithConfig(`{"type":"map","values":"int"}`, goavro.CodecConfig{SortMapKeys: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	datum := map[string]interface{}{"k3": 3, "k1": 1, "k4": 4, "k2": 2}
+	// encode several times to detect any non-deterministic key ordering
+	for i := 0; i < 10; i++ {
+		buf, err := codec.TextualFromNative(nil, datum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := string(buf), `{"k1":1,"k2":2,"k3":3,"k4":4}`; actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+}
+
+func TestMapTextualIndent(t *testing.T) {
+	codec, err := goavro.NewCodecWithConfig(`{"type":"map","values":{"type":"array","items":"int"}}`, goavro.CodecConfig{SortMapKeys: true, Indent: "  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := codec.TextualFromNative([]byte("prefix:"), map[string]interface{}{"k2": []int{}, "k1": []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "prefix:{\n  \"k1\": [\n    1,\n    2\n  ],\n  \"k2\": []\n}"
+	if actual := string(buf); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
 func ExampleMap() {
 	codec, err := goavro.NewCodec(`{
             "name": "r1",
@@ -112,6 +112,20 @@ func newNameFromSchemaMap(enclosingNamespace string, schemaMap map[string]interf
 	return newName(nameString, namespaceString, enclosingNamespace)
 }
 
+// aliasesFromSchemaMap returns the aliases specified in schemaMap. Aliases that
+// are not strings are ignored, because aliases are only used to resolve one
+// schema to another, and have no bearing on encoding or decoding data.
+func aliasesFromSchemaMap(schemaMap map[string]interface{}) []string {
+	values, _ := schemaMap["aliases"].([]interface{})
+	var aliases []string
+	for _, value := range values {
+		if alias, ok := value.(string); ok && alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
 func (n *name) String() string {
 	return n.fullName
 }
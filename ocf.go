@@ -3,9 +3,12 @@ package goavro
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 )
 
 const (
@@ -19,39 +22,36 @@ const (
 	// CompressionSnappyLabel is used when OCF blocks are compressed using the
 	// snappy algorithm.
 	CompressionSnappyLabel = "snappy"
-)
 
-// compressionID are values used to specify compression algorithm used to compress
-// and decompress Avro Object Container File (OCF) streams.
-type compressionID uint8
+	// CompressionZstandardLabel is used when OCF blocks are compressed using
+	// the Zstandard algorithm.
+	CompressionZstandardLabel = "zstandard"
 
-const (
-	compressionNull compressionID = iota
-	compressionDeflate
-	compressionSnappy
+	// CompressionBzip2Label is used when OCF blocks are compressed using the
+	// bzip2 algorithm.
+	CompressionBzip2Label = "bzip2"
+
+	// CompressionXZLabel is used when OCF blocks are compressed using the xz
+	// algorithm.
+	CompressionXZLabel = "xz"
 )
 
 const (
-	ocfBlockConst      = 24 // Each OCF block has two longs prefix, and sync marker suffix
-	ocfHeaderSizeConst = 48 // OCF header is usually about 48 bytes longer than its compressed schema
-	ocfMagicString     = "Obj\x01"
-	ocfMetadataSchema  = `{"type":"map","values":"bytes"}`
-	ocfSyncLength      = 16
+	ocfBlockConst             = 24 // Each OCF block has two longs prefix, and sync marker suffix
+	ocfHeaderSizeConst        = 48 // OCF header is usually about 48 bytes longer than its compressed schema
+	ocfMagicString            = "Obj\x01"
+	ocfReservedMetadataPrefix = "avro." // metadata keys reserved by Avro specification
+	ocfSyncLength             = 16
 )
 
-var (
-	ocfMagicBytes    = []byte(ocfMagicString)
-	ocfMetadataCodec *Codec
-)
-
-func init() {
-	ocfMetadataCodec, _ = NewCodec(ocfMetadataSchema)
-}
+var ocfMagicBytes = []byte(ocfMagicString)
 
 type ocfHeader struct {
-	codec         *Codec
-	compressionID compressionID
-	syncMarker    [ocfSyncLength]byte
+	codec           *Codec
+	compressionName string
+	compressor      Compressor
+	metadata        map[string][]byte // user metadata, excluding reserved avro.* keys
+	syncMarker      [ocfSyncLength]byte
 }
 
 func newOCFHeader(config OCFConfig) (*ocfHeader, error) {
@@ -62,16 +62,12 @@ func newOCFHeader(config OCFConfig) (*ocfHeader, error) {
 	//
 	// avro.codec
 	//
-	switch config.CompressionName {
-	case "":
-		header.compressionID = compressionNull
-	case CompressionNullLabel:
-		header.compressionID = compressionNull
-	case CompressionDeflateLabel:
-		header.compressionID = compressionDeflate
-	case CompressionSnappyLabel:
-		header.compressionID = compressionSnappy
-	default:
+	header.compressionName = config.CompressionName
+	if header.compressionName == "" {
+		header.compressionName = CompressionNullLabel
+	}
+	var ok bool
+	if header.compressor, ok = compressorFromName(header.compressionName); !ok {
 		return nil, fmt.Errorf("cannot create OCF header using unrecognized compression algorithm: %q", config.CompressionName)
 	}
 
@@ -89,9 +85,39 @@ func newOCFHeader(config OCFConfig) (*ocfHeader, error) {
 	}
 
 	//
-	// The 16-byte, randomly-generated sync marker for this file.
+	// user metadata
 	//
-	rand.Read(header.syncMarker[:])
+	if len(config.MetaData) > 0 {
+		header.metadata = make(map[string][]byte, len(config.MetaData))
+		for key, value := range config.MetaData {
+			if strings.HasPrefix(key, ocfReservedMetadataPrefix) {
+				return nil, fmt.Errorf("cannot create OCF header using reserved metadata key: %q", key)
+			}
+			header.metadata[key] = value
+		}
+	}
+
+	//
+	// The 16-byte sync marker for this file, randomly-generated unless
+	// specified or derived from a seed.
+	//
+	switch {
+	case config.SyncMarker != nil && config.SyncMarkerSeed != "":
+		return nil, errors.New("cannot create OCF header with both SyncMarker and SyncMarkerSeed specified")
+	case config.SyncMarker != nil:
+		if len(config.SyncMarker) != ocfSyncLength {
+			return nil, fmt.Errorf("cannot create OCF header when SyncMarker length is not %d: %d", ocfSyncLength, len(config.SyncMarker))
+		}
+		copy(header.syncMarker[:], config.SyncMarker)
+	case config.SyncMarkerSeed != "":
+		hash := sha256.New()
+		hash.Write([]byte(config.SyncMarkerSeed))
+		hash.Write([]byte{0}) // separate seed from schema
+		hash.Write([]byte(header.codec.Schema()))
+		copy(header.syncMarker[:], hash.Sum(nil))
+	default:
+		rand.Read(header.syncMarker[:])
+	}
 
 	return header, nil
 }
@@ -128,25 +154,19 @@ func readOCFHeader(ior io.Reader) (*ocfHeader, error) {
 	// is trivially easy to gracefully handle here, I'm not sure whether this
 	// happens a lot, and don't want to accept bad input unless we have
 	// significant reason to do so.
-	var cID compressionID
-	value, ok := metadata["avro.codec"]
-	if ok {
-		switch avroCodec := string(value); avroCodec {
-		case CompressionNullLabel:
-			cID = compressionNull
-		case CompressionDeflateLabel:
-			cID = compressionDeflate
-		case CompressionSnappyLabel:
-			cID = compressionSnappy
-		default:
-			return nil, fmt.Errorf("cannot read OCF header using unrecognized compression algorithm from avro.codec: %q", avroCodec)
-		}
+	compressionName := CompressionNullLabel
+	if value, ok := metadata["avro.codec"]; ok {
+		compressionName = string(value)
+	}
+	compressor, ok := compressorFromName(compressionName)
+	if !ok {
+		return nil, fmt.Errorf("cannot read OCF header using unrecognized compression algorithm from avro.codec: %q", compressionName)
 	}
 
 	//
 	// create goavro.Codec from specified avro.schema
 	//
-	value, ok = metadata["avro.schema"]
+	value, ok := metadata["avro.schema"]
 	if !ok {
 		return nil, errors.New("cannot read OCF header without avro.schema")
 	}
@@ -155,7 +175,20 @@ func readOCFHeader(ior io.Reader) (*ocfHeader, error) {
 		return nil, fmt.Errorf("cannot read OCF header with invalid avro.schema: %s", err)
 	}
 
-	header := &ocfHeader{codec: codec, compressionID: cID}
+	header := &ocfHeader{codec: codec, compressionName: compressionName, compressor: compressor}
+
+	//
+	// user metadata
+	//
+	for key, value := range metadata {
+		if strings.HasPrefix(key, ocfReservedMetadataPrefix) {
+			continue
+		}
+		if header.metadata == nil {
+			header.metadata = make(map[string][]byte, len(metadata))
+		}
+		header.metadata[key] = value
+	}
 
 	//
 	// read and store sync marker
@@ -171,21 +204,6 @@ func readOCFHeader(ior io.Reader) (*ocfHeader, error) {
 }
 
 func writeOCFHeader(header *ocfHeader, iow io.Writer) (err error) {
-	//
-	// avro.codec
-	//
-	var avroCodec string
-	switch header.compressionID {
-	case compressionNull:
-		avroCodec = CompressionNullLabel
-	case compressionDeflate:
-		avroCodec = CompressionDeflateLabel
-	case compressionSnappy:
-		avroCodec = CompressionSnappyLabel
-	default:
-		return fmt.Errorf("should not get here: cannot write OCF header using unrecognized compression algorithm: %d", header.compressionID)
-	}
-
 	//
 	// avro.schema
 	//
@@ -199,10 +217,25 @@ func writeOCFHeader(header *ocfHeader, iow io.Writer) (err error) {
 	//
 	// file metadata, including the schema
 	//
-	buf, err = ocfMetadataCodec.BinaryFromNative(buf, map[string]interface{}{"avro.schema": []byte(schema), "avro.codec": []byte(avroCodec)})
-	if err != nil {
-		return fmt.Errorf("should not get here: cannot write OCF header: %s", err)
+	// NOTE: Metadata is encoded as a single block of an Avro map, with keys in
+	// sorted order, so the same header is always encoded as the same bytes.
+	metadata := make(map[string][]byte, len(header.metadata)+2)
+	for key, value := range header.metadata {
+		metadata[key] = value
+	}
+	metadata["avro.schema"] = []byte(schema)
+	metadata["avro.codec"] = []byte(header.compressionName)
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	buf, _ = longBinaryFromNative(buf, len(keys))
+	for _, key := range keys {
+		buf, _ = stringBinaryFromNative(buf, key)
+		buf, _ = bytesBinaryFromNative(buf, metadata[key])
 	}
+	buf, _ = longBinaryFromNative(buf, 0) // end of map
 
 	//
 	// 16-byte sync marker
@@ -0,0 +1,441 @@
+package goavro
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	dsbzip2 "github.com/dsnet/compress/bzip2"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compressor is the interface implemented by OCF block compression codecs.
+// Because a single Compressor is registered for each compression name, and
+// then shared by all OCFReader and OCFWriter instances using that compression
+// name, a Compressor must be safe to use from multiple go routines
+// simultaneously.
+type Compressor interface {
+	// Compress appends the compressed form of the src block to dst, and
+	// returns the resulting slice. When dst has sufficient capacity, no
+	// allocation is required.
+	Compress(dst, src []byte) ([]byte, error)
+
+	// Decompress appends the decompressed form of the src block to dst, and
+	// returns the resulting slice. When dst has sufficient capacity, no
+	// allocation is required.
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// LevelCompressor is the interface implemented by a Compressor that supports
+// more than one compression level.
+type LevelCompressor interface {
+	Compressor
+
+	// WithLevel returns a Compressor that compresses blocks using the
+	// specified compression level, or an error when the compression level is
+	// not supported.
+	WithLevel(level int) (Compressor, error)
+}
+
+var (
+	compressorsLock sync.RWMutex
+	compressors     = map[string]Compressor{
+		CompressionNullLabel:      nullCompressor{},
+		CompressionDeflateLabel:   newDeflateCompressor(flate.DefaultCompression),
+		CompressionSnappyLabel:    snappyCompressor{},
+		CompressionZstandardLabel: new(zstdCompressor),
+		CompressionBzip2Label:     bzip2Compressor{},
+		CompressionXZLabel:        xzCompressor{},
+	}
+)
+
+// RegisterCompressor makes the specified Compressor available to OCFReader
+// and OCFWriter instances for OCF files whose avro.codec header metadata value
+// matches the specified name. It returns an error when the name is empty, or
+// a Compressor has already been registered using that name.
+//
+//     func init() {
+//         if err := goavro.RegisterCompressor("lz4", lz4Compressor{}); err != nil {
+//             panic(err)
+//         }
+//     }
+func RegisterCompressor(name string, compressor Compressor) error {
+	if name == "" {
+		return errors.New("cannot register compressor without name")
+	}
+	if compressor == nil {
+		return fmt.Errorf("cannot register nil compressor: %q", name)
+	}
+	compressorsLock.Lock()
+	defer compressorsLock.Unlock()
+	if _, ok := compressors[name]; ok {
+		return fmt.Errorf("cannot register compressor using existing name: %q", name)
+	}
+	compressors[name] = compressor
+	return nil
+}
+
+// compressorFromName returns the Compressor registered using the specified
+// name, or false when no Compressor has been registered using that name.
+func compressorFromName(name string) (Compressor, bool) {
+	compressorsLock.RLock()
+	compressor, ok := compressors[name]
+	compressorsLock.RUnlock()
+	return compressor, ok
+}
+
+// compressorWithLevel returns a Compressor derived from the specified
+// Compressor that uses the specified compression level. A level of 0 always
+// returns the specified Compressor.
+func compressorWithLevel(name string, compressor Compressor, level int) (Compressor, error) {
+	if level == 0 {
+		return compressor, nil // use default level of compression codec
+	}
+	lc, ok := compressor.(LevelCompressor)
+	if !ok {
+		return nil, fmt.Errorf("cannot use compression level with compression codec: %d; %q", level, name)
+	}
+	return lc.WithLevel(level)
+}
+
+// checkLevel returns an error when level is not within [min, max].
+func checkLevel(name string, level, min, max int) error {
+	if level < min || level > max {
+		return fmt.Errorf("cannot use compression level outside range [%d, %d] for %q: %d", min, max, name, level)
+	}
+	return nil
+}
+
+// growBytes returns buf with capacity for at least n more bytes beyond its
+// length, copying its contents to a larger slice only when required.
+func growBytes(buf []byte, n int) []byte {
+	if cap(buf)-len(buf) >= n {
+		return buf
+	}
+	newBuf := make([]byte, len(buf), 2*cap(buf)+n)
+	copy(newBuf, buf)
+	return newBuf
+}
+
+// readAppend reads from ior until io.EOF, appending the bytes read to buf, and
+// returns the resulting slice.
+func readAppend(buf []byte, ior io.Reader) ([]byte, error) {
+	for {
+		buf = growBytes(buf, bytes.MinRead)
+		n, err := ior.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return buf, err
+		}
+	}
+}
+
+//
+// null
+//
+
+type nullCompressor struct{}
+
+func (nullCompressor) Compress(dst, src []byte) ([]byte, error) {
+	return append(dst, src...), nil
+}
+
+func (nullCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	return append(dst, src...), nil
+}
+
+//
+// deflate
+//
+
+// deflateCompressor pools its flate writers and readers, so that compressor
+// state and dictionaries are reused across blocks rather than allocated for
+// each block.
+type deflateCompressor struct {
+	level   int
+	writers sync.Pool // *deflateWriter
+	readers sync.Pool // *deflateReader
+}
+
+// deflateWriter is a flate.Writer that appends compressed bytes to buf.
+type deflateWriter struct {
+	fw  *flate.Writer
+	buf []byte
+}
+
+func (w *deflateWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// deflateReader is a flate reader that decompresses bytes from br.
+type deflateReader struct {
+	rc io.ReadCloser
+	br bytes.Reader
+}
+
+func newDeflateCompressor(level int) *deflateCompressor {
+	return &deflateCompressor{level: level}
+}
+
+func (*deflateCompressor) WithLevel(level int) (Compressor, error) {
+	// NOTE: flate.NoCompression is 0, which is the same as the zero value of
+	// the compression level, and therefore means the default level.
+	if err := checkLevel(CompressionDeflateLabel, level, flate.HuffmanOnly, flate.BestCompression); err != nil {
+		return nil, err
+	}
+	return newDeflateCompressor(level), nil
+}
+
+func (c *deflateCompressor) Compress(dst, src []byte) ([]byte, error) {
+	w, ok := c.writers.Get().(*deflateWriter)
+	if !ok {
+		w = new(deflateWriter)
+		var err error
+		if w.fw, err = flate.NewWriter(w, c.level); err != nil {
+			return nil, err
+		}
+	} else {
+		w.fw.Reset(w)
+	}
+	w.buf = dst
+	// writing bytes to fw will compress bytes and append them to w.buf.
+	if _, err := w.fw.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.fw.Close(); err != nil {
+		return nil, err
+	}
+	dst, w.buf = w.buf, nil
+	c.writers.Put(w)
+	return dst, nil
+}
+
+func (c *deflateCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	r, ok := c.readers.Get().(*deflateReader)
+	if !ok {
+		r = new(deflateReader)
+		r.br.Reset(src)
+		// NOTE: flate.NewReader wraps with io.ByteReader if argument does
+		// not implement that interface.
+		r.rc = flate.NewReader(&r.br)
+	} else {
+		r.br.Reset(src)
+		if err := r.rc.(flate.Resetter).Reset(&r.br, nil); err != nil {
+			return nil, err
+		}
+	}
+	dst, err := readAppend(dst, r.rc)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.rc.Close(); err != nil {
+		return nil, err
+	}
+	r.br.Reset(nil)
+	c.readers.Put(r)
+	return dst, nil
+}
+
+//
+// snappy
+//
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Compress(dst, src []byte) ([]byte, error) {
+	// Encode directly into the spare capacity of dst, growing it when
+	// required, so that no temporary buffer is needed.
+	maxLen := snappy.MaxEncodedLen(len(src))
+	if maxLen < 0 {
+		return nil, fmt.Errorf("cannot compress snappy block too large: %d", len(src))
+	}
+	dst = growBytes(dst, maxLen+4)
+	encoded := snappy.Encode(dst[len(dst):len(dst)+maxLen], src)
+	dst = dst[:len(dst)+len(encoded)]
+
+	// OCF requires snappy to have CRC32 checksum after each snappy block
+	dst = append(dst, 0, 0, 0, 0)                                         // expand slice by 4 bytes so checksum will fit
+	binary.BigEndian.PutUint32(dst[len(dst)-4:], crc32.ChecksumIEEE(src)) // checksum of decompressed block
+	return dst, nil
+}
+
+func (snappyCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	index := len(src) - 4 // last 4 bytes is crc32 of decoded block
+	if index <= 0 {
+		return nil, fmt.Errorf("cannot decompress snappy without CRC32 checksum: %d", len(src))
+	}
+	decodedLen, err := snappy.DecodedLen(src[:index])
+	if err != nil {
+		return nil, err
+	}
+	dst = growBytes(dst, decodedLen)
+	decoded, err := snappy.Decode(dst[len(dst):len(dst)+decodedLen], src[:index])
+	if err != nil {
+		return nil, err
+	}
+	actualCRC := crc32.ChecksumIEEE(decoded)
+	expectedCRC := binary.BigEndian.Uint32(src[index : index+4])
+	if actualCRC != expectedCRC {
+		return nil, fmt.Errorf("snappy CRC32 checksum mismatch: %x != %x", actualCRC, expectedCRC)
+	}
+	return dst[:len(dst)+len(decoded)], nil
+}
+
+//
+// zstandard
+//
+
+// zstdCompressor lazily creates its encoder and decoder, both of which are
+// safe for concurrent use, the first time they are needed.
+type zstdCompressor struct {
+	level       int // 0 for default
+	encoderOnce sync.Once
+	encoder     *zstd.Encoder
+	encoderErr  error
+	decoderOnce sync.Once
+	decoder     *zstd.Decoder
+	decoderErr  error
+}
+
+func (*zstdCompressor) WithLevel(level int) (Compressor, error) {
+	if err := checkLevel(CompressionZstandardLabel, level, 1, 22); err != nil {
+		return nil, err
+	}
+	return &zstdCompressor{level: level}, nil
+}
+
+func (c *zstdCompressor) Compress(dst, src []byte) ([]byte, error) {
+	c.encoderOnce.Do(func() {
+		var options []zstd.EOption
+		if c.level > 0 {
+			options = append(options, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.level)))
+		}
+		c.encoder, c.encoderErr = zstd.NewWriter(nil, options...)
+	})
+	if c.encoderErr != nil {
+		return nil, c.encoderErr
+	}
+	return c.encoder.EncodeAll(src, dst), nil
+}
+
+func (c *zstdCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	c.decoderOnce.Do(func() {
+		c.decoder, c.decoderErr = zstd.NewReader(nil)
+	})
+	if c.decoderErr != nil {
+		return nil, c.decoderErr
+	}
+	return c.decoder.DecodeAll(src, dst)
+}
+
+//
+// bzip2
+//
+
+type bzip2Compressor struct {
+	level int // 0 for default
+}
+
+func (bzip2Compressor) WithLevel(level int) (Compressor, error) {
+	if err := checkLevel(CompressionBzip2Label, level, dsbzip2.BestSpeed, dsbzip2.BestCompression); err != nil {
+		return nil, err
+	}
+	return bzip2Compressor{level: level}, nil
+}
+
+func (c bzip2Compressor) Compress(dst, src []byte) ([]byte, error) {
+	var config *dsbzip2.WriterConfig
+	if c.level > 0 {
+		config = &dsbzip2.WriterConfig{Level: c.level}
+	}
+	bb := bytes.NewBuffer(dst)
+	cw, err := dsbzip2.NewWriter(bb, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = cw.Write(src); err != nil {
+		return nil, err
+	}
+	if err = cw.Close(); err != nil {
+		return nil, err
+	}
+	return bb.Bytes(), nil
+}
+
+func (bzip2Compressor) Decompress(dst, src []byte) ([]byte, error) {
+	return readAppend(dst, bzip2.NewReader(bytes.NewReader(src)))
+}
+
+//
+// xz
+//
+
+// xzDictCapFromPreset maps the xz command line presets to the dictionary
+// capacities they use. The dictionary capacity is the only parameter of a
+// preset applied, because the xz package has no equivalent of the nice length
+// and search depth of presets, and its binary tree match finder, which xz uses
+// from preset 4, compresses far worse and slower than its hash table match
+// finder. Blocks are usually smaller than even the dictionary of preset 1, so
+// the preset rarely changes the compressed block.
+var xzDictCapFromPreset = [...]int{
+	256 << 10, // 0
+	1 << 20,   // 1
+	2 << 20,   // 2
+	4 << 20,   // 3
+	4 << 20,   // 4
+	8 << 20,   // 5
+	8 << 20,   // 6 (default)
+	16 << 20,  // 7
+	32 << 20,  // 8
+	64 << 20,  // 9
+}
+
+type xzCompressor struct {
+	level int // 0 for default
+}
+
+func (xzCompressor) WithLevel(level int) (Compressor, error) {
+	if err := checkLevel(CompressionXZLabel, level, 1, 9); err != nil {
+		return nil, err
+	}
+	return xzCompressor{level: level}, nil
+}
+
+func (c xzCompressor) Compress(dst, src []byte) ([]byte, error) {
+	var config xz.WriterConfig
+	if c.level > 0 {
+		config.DictCap = xzDictCapFromPreset[c.level]
+	}
+	bb := bytes.NewBuffer(dst)
+	cw, err := config.NewWriter(bb)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = cw.Write(src); err != nil {
+		return nil, err
+	}
+	if err = cw.Close(); err != nil {
+		return nil, err
+	}
+	return bb.Bytes(), nil
+}
+
+func (xzCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	rc, err := xz.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	return readAppend(dst, rc)
+}
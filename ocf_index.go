@@ -0,0 +1,282 @@
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// ocfIndexMagicString is written at the start of a persisted OCFIndex.
+const ocfIndexMagicString = "OCFi\x01"
+
+// OCFIndex is an index of the blocks of an OCF, used by SeekToRecord to
+// position a reader at any data item without reading the blocks preceding it.
+type OCFIndex struct {
+	// SyncMarker is the sync marker of the indexed OCF, used to ensure the
+	// index is only used with the OCF from which it was built.
+	SyncMarker [ocfSyncLength]byte
+
+	// Size is the number of bytes of the indexed OCF. Because blocks may only
+	// be appended to an OCF, an index remains valid for the data items it
+	// indexes after more blocks are appended to the OCF.
+	Size int64
+
+	// Blocks describes each block of the OCF, in order.
+	Blocks []OCFIndexBlock
+}
+
+// OCFIndexBlock describes a block of an OCF in an OCFIndex.
+type OCFIndexBlock struct {
+	// Offset is the offset of the block from the start of the OCF.
+	Offset int64
+
+	// First is the record number of the first data item of the block, which
+	// is the number of data items in all preceding blocks.
+	First int64
+
+	// Count is the number of data items in the block.
+	Count int64
+}
+
+// Count returns the number of data items in the indexed OCF.
+func (index *OCFIndex) Count() int64 {
+	if len(index.Blocks) == 0 {
+		return 0
+	}
+	last := index.Blocks[len(index.Blocks)-1]
+	return last.First + last.Count
+}
+
+// BuildIndex reads the block count and block size of each block of the OCF,
+// skipping its data items in the same way as appending to an existing OCF,
+// and returns an index of the blocks. It does not change the position of the
+// reader.
+//
+// This method returns an error when the reader was not created by
+// NewOCFReaderAt.
+func (ocfr *OCFReader) BuildIndex() (*OCFIndex, error) {
+	if ocfr.ra == nil {
+		return nil, errors.New("cannot build index of OCFReader not created by NewOCFReaderAt")
+	}
+	index := &OCFIndex{SyncMarker: ocfr.header.syncMarker, Size: ocfr.size}
+
+	// NOTE: Block count and block size are each encoded using at most 10
+	// bytes.
+	prefix := make([]byte, 20)
+	sync := make([]byte, ocfSyncLength)
+	var first int64
+
+	for offset := ocfr.dataStart; offset < ocfr.size; {
+		n, err := ocfr.ra.ReadAt(prefix, offset)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("cannot build index: %s", err)
+		}
+		if remaining := ocfr.size - offset; int64(n) > remaining {
+			n = int(remaining) // ignore bytes beyond declared size
+		}
+		buf := prefix[:n]
+
+		var value interface{}
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, fmt.Errorf("cannot build index: cannot read block count at offset %d: %s", offset, err)
+		}
+		blockCount := value.(int64)
+		if blockCount <= 0 {
+			return nil, fmt.Errorf("cannot build index: block count is not greater than 0 at offset %d: %d", offset, blockCount)
+		}
+		if blockCount > MaxBlockCount {
+			return nil, fmt.Errorf("cannot build index: block count exceeds MaxBlockCount at offset %d: %d > %d", offset, blockCount, MaxBlockCount)
+		}
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, fmt.Errorf("cannot build index: cannot read block size at offset %d: %s", offset, err)
+		}
+		blockSize := value.(int64)
+		if blockSize <= 0 {
+			return nil, fmt.Errorf("cannot build index: block size is not greater than 0 at offset %d: %d", offset, blockSize)
+		}
+		if blockSize > MaxBlockSize {
+			return nil, fmt.Errorf("cannot build index: block size exceeds MaxBlockSize at offset %d: %d > %d", offset, blockSize, MaxBlockSize)
+		}
+
+		// Read and validate sync marker following block
+		end := offset + int64(n-len(buf)) + blockSize
+		if end+ocfSyncLength > ocfr.size {
+			return nil, fmt.Errorf("cannot build index: cannot read sync marker at offset %d: %s", end, io.ErrUnexpectedEOF)
+		}
+		if _, err = ocfr.ra.ReadAt(sync, end); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("cannot build index: cannot read sync marker at offset %d: %s", end, err)
+		}
+		if !bytes.Equal(sync, ocfr.header.syncMarker[:]) {
+			return nil, fmt.Errorf("cannot build index: sync marker mismatch at offset %d: %v != %v", end, sync, ocfr.header.syncMarker)
+		}
+
+		index.Blocks = append(index.Blocks, OCFIndexBlock{Offset: offset, First: first, Count: blockCount})
+		first += blockCount
+		offset = end + ocfSyncLength
+	}
+
+	return index, nil
+}
+
+// SeekToRecord positions the reader at the data item whose record number is
+// n, such that the following Scan and Read return it, by reading only the
+// block containing it, and decoding and discarding the data items preceding
+// it within the block. Record numbers start at 0. When n is the number of
+// data items in the index, the reader is positioned at the end of the indexed
+// blocks. Any data items remaining in the current block are discarded, and
+// any previous error is cleared.
+//
+// This method returns an error when the reader was not created by
+// NewOCFReaderAt, or when the index was not built from the same OCF.
+//
+//     func page(ocfr *goavro.OCFReader, index *goavro.OCFIndex, first, count int64) ([]interface{}, error) {
+//         if err := ocfr.SeekToRecord(index, first); err != nil {
+//             return nil, err
+//         }
+//         var data []interface{}
+//         for int64(len(data)) < count && ocfr.Scan() {
+//             datum, err := ocfr.Read()
+//             if err != nil {
+//                 return nil, err
+//             }
+//             data = append(data, datum)
+//         }
+//         return data, ocfr.Err()
+//     }
+func (ocfr *OCFReader) SeekToRecord(index *OCFIndex, n int64) error {
+	if ocfr.ra == nil {
+		return errors.New("cannot seek OCFReader not created by NewOCFReaderAt")
+	}
+	if index.SyncMarker != ocfr.header.syncMarker {
+		return errors.New("cannot seek to record using index of a different OCF")
+	}
+	if index.Size > ocfr.size {
+		return fmt.Errorf("cannot seek to record using index of OCF larger than OCF: %d > %d", index.Size, ocfr.size)
+	}
+	if count := index.Count(); n < 0 || n > count {
+		return fmt.Errorf("cannot seek to record outside range [0, %d]: %d", count, n)
+	}
+
+	ocfr.block = nil
+	ocfr.rerr = nil
+	ocfr.derr = nil
+	ocfr.readReady = false
+	ocfr.remainingBlockItems = 0
+
+	i := sort.Search(len(index.Blocks), func(i int) bool {
+		return n < index.Blocks[i].First+index.Blocks[i].Count
+	})
+	if i == len(index.Blocks) {
+		// NOTE: Position reader at end of indexed blocks.
+		ocfr.or = newOffsetReader(ocfr.ra, index.Size, ocfr.size)
+		ocfr.ior = ocfr.or
+		return nil
+	}
+	entry := index.Blocks[i]
+	ocfr.or = newOffsetReader(ocfr.ra, entry.Offset, ocfr.size)
+	ocfr.ior = ocfr.or
+
+	// NOTE: Read the block containing the record, then discard the data items
+	// preceding it.
+	count, block, err := ocfr.readBlock()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return fmt.Errorf("cannot seek to record %d: %s", n, err)
+	}
+	if count != entry.Count {
+		return fmt.Errorf("cannot seek to record %d: block count does not match index: %d != %d", n, count, entry.Count)
+	}
+	if ocfr.block, err = ocfr.header.compressor.Decompress(nil, block); err != nil {
+		return fmt.Errorf("cannot seek to record %d: cannot decompress: %s", n, err)
+	}
+	ocfr.remainingBlockItems = count
+	for skip := n - entry.First; skip > 0; skip-- {
+		if _, ocfr.block, err = ocfr.header.codec.NativeFromBinary(ocfr.block); err != nil {
+			ocfr.block = nil
+			ocfr.remainingBlockItems = 0
+			return fmt.Errorf("cannot seek to record %d: %s", n, err)
+		}
+		ocfr.remainingBlockItems--
+	}
+	return nil
+}
+
+// WriteTo writes the index to iow, in a form which may be read by
+// ReadOCFIndex, such as to persist it in a sidecar file alongside the OCF.
+func (index *OCFIndex) WriteTo(iow io.Writer) (int64, error) {
+	buf := make([]byte, 0, len(ocfIndexMagicString)+ocfSyncLength+10*(2+2*len(index.Blocks)))
+	buf = append(buf, ocfIndexMagicString...)
+	buf = append(buf, index.SyncMarker[:]...)
+	buf, _ = longBinaryFromNative(buf, index.Size)
+	buf, _ = longBinaryFromNative(buf, len(index.Blocks))
+	for _, block := range index.Blocks {
+		buf, _ = longBinaryFromNative(buf, block.Offset)
+		buf, _ = longBinaryFromNative(buf, block.Count)
+	}
+	n, err := iow.Write(buf)
+	if err != nil {
+		return int64(n), fmt.Errorf("cannot write index: %s", err)
+	}
+	return int64(n), nil
+}
+
+// ReadOCFIndex reads an index written by OCFIndex.WriteTo from ior.
+func ReadOCFIndex(ior io.Reader) (*OCFIndex, error) {
+	magic := make([]byte, len(ocfIndexMagicString))
+	if _, err := io.ReadFull(ior, magic); err != nil {
+		return nil, fmt.Errorf("cannot read index magic bytes: %s", err)
+	}
+	if string(magic) != ocfIndexMagicString {
+		return nil, fmt.Errorf("cannot read index with invalid magic bytes: %#q", magic)
+	}
+	index := new(OCFIndex)
+	if _, err := io.ReadFull(ior, index.SyncMarker[:]); err != nil {
+		return nil, fmt.Errorf("cannot read index sync marker: %s", err)
+	}
+	var err error
+	if index.Size, err = longBinaryReader(ior); err != nil {
+		return nil, fmt.Errorf("cannot read index size: %s", err)
+	}
+	if index.Size < 0 {
+		return nil, fmt.Errorf("cannot read index with negative size: %d", index.Size)
+	}
+	count, err := longBinaryReader(ior)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read index block count: %s", err)
+	}
+	// NOTE: Each block has at least one byte for each of its count and size,
+	// followed by its sync marker.
+	if count < 0 || count > index.Size/(ocfSyncLength+2) {
+		return nil, fmt.Errorf("cannot read index with invalid block count: %d", count)
+	}
+	// NOTE: Do not allocate more blocks than have been read, because the block
+	// count of a corrupt index could still be very large.
+	var first int64
+	for i := int64(0); i < count; i++ {
+		var block OCFIndexBlock
+		if block.Offset, err = longBinaryReader(ior); err != nil {
+			return nil, fmt.Errorf("cannot read index block offset: %s", err)
+		}
+		if block.Count, err = longBinaryReader(ior); err != nil {
+			return nil, fmt.Errorf("cannot read index block count: %s", err)
+		}
+		if block.Offset < 0 || block.Offset >= index.Size {
+			return nil, fmt.Errorf("cannot read index block %d with invalid offset: %d", i, block.Offset)
+		}
+		if i > 0 && block.Offset <= index.Blocks[i-1].Offset {
+			return nil, fmt.Errorf("cannot read index block %d when offset not greater than previous: %d <= %d", i, block.Offset, index.Blocks[i-1].Offset)
+		}
+		if block.Count < 0 || block.Count > math.MaxInt64-first {
+			return nil, fmt.Errorf("cannot read index block %d with invalid count: %d", i, block.Count)
+		}
+		block.First = first
+		first += block.Count
+		index.Blocks = append(index.Blocks, block)
+	}
+	return index, nil
+}
@@ -0,0 +1,193 @@
+package goavro_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+// newOCFIndexOfLongs returns a reader of the OCF in buf, and an index of it.
+func newOCFIndexOfLongs(t *testing.T, buf []byte) (*goavro.OCFReader, *goavro.OCFIndex) {
+	ocfr, err := goavro.NewOCFReaderAt(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := ocfr.BuildIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ocfr, index
+}
+
+func TestOCFReaderBuildIndex(t *testing.T) {
+	buf := newOCFOfLongs(t, 1000, 37, goavro.CompressionDeflateLabel)
+	syncs := ocfSyncOffsets(buf)
+	_, index := newOCFIndexOfLongs(t, buf)
+
+	if actual, expected := index.Count(), int64(1000); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := index.Size, int64(len(buf)); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := index.SyncMarker[:], buf[len(buf)-16:]; !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := len(index.Blocks), 28; actual != expected {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	for i, block := range index.Blocks {
+		expected := goavro.OCFIndexBlock{Offset: int64(syncs[i] + 16), First: int64(37 * i), Count: 37}
+		if i == 27 {
+			expected.Count = 1000 - 37*27
+		}
+		if actual := block; actual != expected {
+			t.Errorf("Actual: %+v; Expected: %+v", actual, expected)
+		}
+	}
+
+	// reader not created by NewOCFReaderAt cannot build index
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ocfr.BuildIndex()
+	ensureError(t, err, "cannot build index")
+
+	// corrupt sync marker
+	buf[syncs[3]+5] ^= 0xff
+	ocfr, err = goavro.NewOCFReaderAt(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ocfr.BuildIndex()
+	ensureError(t, err, "cannot build index", "sync marker mismatch", "offset")
+}
+
+func TestOCFReaderSeekToRecord(t *testing.T) {
+	buf := newOCFOfLongs(t, 1000, 37, goavro.CompressionSnappyLabel)
+	ocfr, index := newOCFIndexOfLongs(t, buf)
+
+	for _, n := range []int64{0, 1, 36, 37, 38, 500, 998, 999, 1000, 3} {
+		if err := ocfr.SeekToRecord(index, n); err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := scanOCFLongs(t, ocfr), expectedLongs(1000, 0, int(n)); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("n: %d; Actual: %v; Expected: %v", n, actual, expected)
+		}
+	}
+
+	ensureError(t, ocfr.SeekToRecord(index, -1), "cannot seek to record outside range [0, 1000]: -1")
+	ensureError(t, ocfr.SeekToRecord(index, 1001), "cannot seek to record outside range [0, 1000]: 1001")
+
+	// index of different OCF
+	_, other := newOCFIndexOfLongs(t, newOCFOfLongs(t, 1000, 37, goavro.CompressionSnappyLabel))
+	ensureError(t, ocfr.SeekToRecord(other, 0), "cannot seek to record", "different OCF")
+
+	// reader not created by NewOCFReaderAt cannot seek
+	serial, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, serial.SeekToRecord(index, 0), "cannot seek")
+}
+
+func TestOCFIndexSidecar(t *testing.T) {
+	mf := new(memFile)
+	appendOCFLongs(t, goavro.OCFConfig{W: mf, Schema: `"long"`, BlockCount: 10}, 0, 100)
+	_, index := newOCFIndexOfLongs(t, mf.buf)
+
+	bb := new(bytes.Buffer)
+	n, err := index.WriteTo(bb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := n, int64(bb.Len()); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	sidecar, err := goavro.ReadOCFIndex(bytes.NewReader(bb.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := sidecar, index; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %+v; Expected: %+v", actual, expected)
+	}
+
+	// index remains valid after blocks are appended to OCF
+	appendOCFLongs(t, goavro.OCFConfig{W: mf}, 100, 150)
+	ocfr, err := goavro.NewOCFReaderAt(bytes.NewReader(mf.buf), int64(len(mf.buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfr.SeekToRecord(sidecar, 95); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := scanOCFLongs(t, ocfr), expectedLongs(150, 0, 95); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// index of larger OCF
+	truncated := mf.buf[:index.Size-1]
+	ocfr, err = goavro.NewOCFReaderAt(bytes.NewReader(truncated), int64(len(truncated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfr.SeekToRecord(sidecar, 0), "cannot seek to record", "larger than OCF")
+
+	_, err = goavro.ReadOCFIndex(bytes.NewReader([]byte("not an index")))
+	ensureError(t, err, "cannot read index", "invalid magic bytes")
+
+	_, err = goavro.ReadOCFIndex(bytes.NewReader(bb.Bytes()[:bb.Len()-1]))
+	ensureError(t, err, "cannot read index block")
+}
+
+func TestOCFIndexSidecarCorrupt(t *testing.T) {
+	mf := new(memFile)
+	appendOCFLongs(t, goavro.OCFConfig{W: mf, Schema: `"long"`, BlockCount: 10}, 0, 30)
+	_, index := newOCFIndexOfLongs(t, mf.buf)
+
+	bb := new(bytes.Buffer)
+	if _, err := index.WriteTo(bb); err != nil {
+		t.Fatal(err)
+	}
+	header := bb.Bytes()[:len("OCFi\x01")+16] // magic bytes and sync marker
+
+	// sidecar returns header followed by the specified longs
+	sidecar := func(values ...int64) []byte {
+		buf := append([]byte(nil), header...)
+		for _, value := range values {
+			var b [binary.MaxVarintLen64]byte // zig-zag encoded like Avro long
+			buf = append(buf, b[:binary.PutVarint(b[:], value)]...)
+		}
+		return buf
+	}
+
+	tests := []struct {
+		name   string
+		values []int64
+		errs   []string
+	}{
+		{"huge count", []int64{math.MaxInt64, math.MaxInt64}, []string{"invalid block count"}},
+		{"huge count within huge size", []int64{math.MaxInt64, math.MaxInt64 / 18}, []string{"cannot read index block offset"}},
+		{"count too large for size", []int64{100, 6}, []string{"invalid block count: 6"}},
+		{"negative size", []int64{-1, 0}, []string{"negative size: -1"}},
+		{"negative offset", []int64{1000, 1, -5, 10}, []string{"invalid offset: -5"}},
+		{"offset beyond size", []int64{1000, 1, 1000, 10}, []string{"invalid offset: 1000"}},
+		{"negative count", []int64{1000, 1, 50, -10}, []string{"invalid count: -10"}},
+		{"count overflow", []int64{1000, 2, 50, math.MaxInt64, 100, 1}, []string{"block 1 with invalid count: 1"}},
+		{"decreasing offset", []int64{1000, 2, 100, 10, 50, 10}, []string{"block 1 when offset not greater than previous: 50 <= 100"}},
+		{"repeated offset", []int64{1000, 2, 100, 10, 100, 10}, []string{"not greater than previous"}},
+	}
+	for _, test := range tests {
+		_, err := goavro.ReadOCFIndex(bytes.NewReader(sidecar(test.values...)))
+		if err == nil {
+			t.Errorf("%s: Actual: %v; Expected: %v", test.name, err, test.errs)
+			continue
+		}
+		ensureError(t, err, test.errs...)
+	}
+}
@@ -0,0 +1,269 @@
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// MultiOCFReaderConfig is used to specify creation parameters for a
+// MultiOCFReader.
+type MultiOCFReaderConfig struct {
+	// ReaderSchema specifies the schema of the data items returned by Read,
+	// (optional). If omitted, each data item is returned as decoded using the
+	// schema of the OCF from which it was read. Otherwise the schema of each
+	// OCF is resolved to this schema following the Avro schema resolution
+	// rules, which supply default values for record fields missing from the
+	// schema of the OCF, drop record fields missing from this schema, match
+	// named types and record fields by name or alias, promote numeric values,
+	// and promote values into and out of unions. Scan returns false when the
+	// schema of an OCF cannot be resolved to this schema, and Read returns an
+	// error when a data item uses a union member or enum symbol that cannot be
+	// resolved.
+	ReaderSchema string
+
+	// SkipOCF specifies a function called once the header of each OCF has
+	// been read, which returns true when the OCF is to be skipped without
+	// reading any of its blocks, (optional). It is provided the index of the
+	// OCF, its pathname, or the empty string when it was not opened from a
+	// pathname, and its reader, from which its metadata may be inspected.
+	SkipOCF func(index int, pathname string, ocfr *OCFReader) bool
+}
+
+// MultiOCFReader reads the data items of a sequence of Avro Object Container
+// Files (OCF) as a single logical stream, using the same Scan and Read API as
+// OCFReader.
+type MultiOCFReader struct {
+	config      MultiOCFReaderConfig
+	readerCodec *Codec // when not nil, codec of ReaderSchema
+
+	count int                                  // count of OCFs
+	open  func(int) (io.Reader, string, error) // returns reader and pathname of OCF
+
+	index    int            // index of current OCF
+	pathname string         // pathname of current OCF
+	closer   io.Closer      // closes current OCF, when opened by MultiOCFReader
+	ocfr     *OCFReader     // reader of current OCF
+	resolve  nativeResolver // when not nil, resolves data items of current OCF to ReaderSchema
+	block    int64          // index of block of current OCF holding most recently scanned data item
+	err      error
+}
+
+// NewMultiOCFReader returns a MultiOCFReader which reads each of the provided
+// OCFs in turn.
+//
+//     func example(readers []io.Reader) error {
+//         mr, err := goavro.NewMultiOCFReader(readers...)
+//         if err != nil {
+//             return err
+//         }
+//         defer mr.Close()
+//         for mr.Scan() {
+//             datum, err := mr.Read()
+//             if err != nil {
+//                 return err
+//             }
+//             fmt.Println(mr.Index(), mr.Block(), datum)
+//         }
+//         return mr.Err()
+//     }
+func NewMultiOCFReader(readers ...io.Reader) (*MultiOCFReader, error) {
+	return NewMultiOCFReaderWithConfig(MultiOCFReaderConfig{}, readers...)
+}
+
+// NewMultiOCFReaderWithConfig returns a MultiOCFReader which reads each of the
+// provided OCFs in turn, using the specified configuration.
+func NewMultiOCFReaderWithConfig(config MultiOCFReaderConfig, readers ...io.Reader) (*MultiOCFReader, error) {
+	for i, ior := range readers {
+		if ior == nil {
+			return nil, fmt.Errorf("cannot create MultiOCFReader when reader is nil: %d", i)
+		}
+	}
+	open := func(i int) (io.Reader, string, error) { return readers[i], "", nil }
+	return newMultiOCFReader(config, len(readers), open)
+}
+
+// NewMultiOCFReaderGlob returns a MultiOCFReader which reads each of the OCF
+// files whose pathnames match pattern, as described by filepath.Glob, in
+// lexical order. It returns an error when no file matches pattern. Each file
+// is opened once the previous file has been read, and closed once read, so the
+// MultiOCFReader must be released by calling its Close method when no longer
+// needed.
+func NewMultiOCFReaderGlob(pattern string, config MultiOCFReaderConfig) (*MultiOCFReader, error) {
+	pathnames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create MultiOCFReader: %s", err)
+	}
+	if len(pathnames) == 0 {
+		return nil, fmt.Errorf("cannot create MultiOCFReader when no files match pattern: %q", pattern)
+	}
+	open := func(i int) (io.Reader, string, error) {
+		fh, err := os.Open(pathnames[i])
+		if err != nil {
+			return nil, pathnames[i], err
+		}
+		return fh, pathnames[i], nil
+	}
+	return newMultiOCFReader(config, len(pathnames), open)
+}
+
+func newMultiOCFReader(config MultiOCFReaderConfig, count int, open func(int) (io.Reader, string, error)) (*MultiOCFReader, error) {
+	mr := &MultiOCFReader{config: config, count: count, open: open, index: -1, block: -1}
+	if config.ReaderSchema != "" {
+		var err error
+		if mr.readerCodec, err = NewCodec(config.ReaderSchema); err != nil {
+			return nil, fmt.Errorf("cannot create MultiOCFReader: %s", err)
+		}
+	}
+	return mr, nil
+}
+
+// Codec returns the codec of the data items returned by Read, which is the
+// codec of ReaderSchema when specified, and otherwise the codec of the OCF
+// being read, or nil before the first successful call to Scan.
+func (mr *MultiOCFReader) Codec() *Codec {
+	if mr.readerCodec != nil {
+		return mr.readerCodec
+	}
+	if mr.ocfr == nil {
+		return nil
+	}
+	return mr.ocfr.Codec()
+}
+
+// Index returns the index of the OCF from which the most recently scanned data
+// item is read.
+func (mr *MultiOCFReader) Index() int {
+	return mr.index
+}
+
+// Pathname returns the pathname of the OCF from which the most recently
+// scanned data item is read, or the empty string when the OCF was not opened
+// from a pathname.
+func (mr *MultiOCFReader) Pathname() string {
+	return mr.pathname
+}
+
+// Block returns the index of the block, within its OCF, from which the most
+// recently scanned data item is read.
+func (mr *MultiOCFReader) Block() int64 {
+	return mr.block
+}
+
+// Err returns the last error encountered while reading the OCFs.
+func (mr *MultiOCFReader) Err() error {
+	return mr.err
+}
+
+// Close closes the OCF file being read, when it was opened by the
+// MultiOCFReader, after which Scan returns false.
+func (mr *MultiOCFReader) Close() error {
+	mr.index = mr.count
+	mr.ocfr = nil
+	return mr.closeCurrent()
+}
+
+func (mr *MultiOCFReader) closeCurrent() error {
+	if mr.closer == nil {
+		return nil
+	}
+	err := mr.closer.Close()
+	mr.closer = nil
+	return err
+}
+
+// Scan returns true when there is at least one more data item to be read from
+// the OCFs, opening the next OCF when necessary. Scan ought to be called prior
+// to calling the Read method each time the Read method is invoked.
+func (mr *MultiOCFReader) Scan() bool {
+	for mr.err == nil {
+		if mr.ocfr != nil {
+			newBlock := mr.ocfr.RemainingBlockItems() <= 0
+			if mr.ocfr.Scan() {
+				if newBlock {
+					mr.block++
+				}
+				return true
+			}
+			if mr.err = mr.ocfr.Err(); mr.err != nil {
+				mr.err = fmt.Errorf("cannot read OCF %s: %s", mr.describe(), mr.err)
+				return false
+			}
+			mr.ocfr = nil
+			if mr.err = mr.closeCurrent(); mr.err != nil {
+				return false
+			}
+		}
+		if mr.index+1 >= mr.count {
+			return false
+		}
+		mr.err = mr.openNext()
+	}
+	return false
+}
+
+// openNext opens the next OCF, unless it is skipped.
+func (mr *MultiOCFReader) openNext() error {
+	mr.index++
+	mr.block = -1
+	ior, pathname, err := mr.open(mr.index)
+	mr.pathname = pathname
+	if err != nil {
+		return fmt.Errorf("cannot open OCF %s: %s", mr.describe(), err)
+	}
+	if closer, ok := ior.(io.Closer); ok && pathname != "" {
+		mr.closer = closer
+	}
+	ocfr, err := NewOCFReader(ior)
+	if err != nil {
+		_ = mr.closeCurrent()
+		return fmt.Errorf("cannot read OCF %s: %s", mr.describe(), err)
+	}
+	if mr.config.SkipOCF != nil && mr.config.SkipOCF(mr.index, pathname, ocfr) {
+		return mr.closeCurrent()
+	}
+	mr.resolve = nil
+	if mr.readerCodec != nil && mr.readerCodec.CanonicalSchema() != ocfr.Codec().CanonicalSchema() {
+		if mr.resolve, err = newNativeResolver(ocfr.Codec(), mr.readerCodec); err != nil {
+			_ = mr.closeCurrent()
+			return fmt.Errorf("cannot resolve schema of OCF %s to reader schema: %s", mr.describe(), err)
+		}
+	}
+	mr.ocfr = ocfr
+	return nil
+}
+
+// describe returns a description of the current OCF for error messages.
+func (mr *MultiOCFReader) describe() string {
+	if mr.pathname != "" {
+		return fmt.Sprintf("%q", mr.pathname)
+	}
+	return fmt.Sprintf("%d", mr.index)
+}
+
+// Read consumes one datum value from the OCFs and returns it. Read is designed
+// to be called only once after each invocation of the Scan method.
+func (mr *MultiOCFReader) Read() (interface{}, error) {
+	if mr.err != nil {
+		return nil, mr.err
+	}
+	if mr.ocfr == nil {
+		mr.err = errors.New("Read called without successful Scan")
+		return nil, mr.err
+	}
+	datum, err := mr.ocfr.Read()
+	if err != nil {
+		mr.err = fmt.Errorf("cannot read OCF %s: %s", mr.describe(), err)
+		return nil, mr.err
+	}
+	if mr.resolve == nil {
+		return datum, nil
+	}
+	if datum, err = mr.resolve(datum); err != nil {
+		mr.err = fmt.Errorf("cannot resolve datum from OCF %s to reader schema: %s", mr.describe(), err)
+		return nil, mr.err
+	}
+	return datum, nil
+}
@@ -0,0 +1,335 @@
+package goavro_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+// newOCFOfRecords returns an OCF using schema, holding the provided data
+// items, and having the provided metadata.
+func newOCFOfRecords(t *testing.T, schema string, metadata map[string][]byte, data ...interface{}) []byte {
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: bb, Schema: schema, MetaData: metadata})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Append(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+func TestMultiOCFReader(t *testing.T) {
+	var readers []io.Reader
+	for i, compressionName := range []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		bb := new(bytes.Buffer)
+		appendOCFLongs(t, goavro.OCFConfig{W: bb, Schema: `"long"`, CompressionName: compressionName, BlockCount: 10}, 100*i, 100*i+25)
+		readers = append(readers, bb)
+	}
+
+	mr, err := goavro.NewMultiOCFReader(readers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	var values []int64
+	var positions []string
+	for mr.Scan() {
+		datum, err := mr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := mr.Codec().Schema(), `"long"`; actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+		values = append(values, datum.(int64))
+		if datum.(int64)%5 == 0 {
+			positions = append(positions, fmt.Sprintf("%d:%d:%d", datum, mr.Index(), mr.Block()))
+		}
+	}
+	if err = mr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := values, append(append(expectedLongs(25), expectedLongs(125, 0, 100)...), expectedLongs(225, 0, 200)...); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := positions, []string{
+		"0:0:0", "5:0:0", "10:0:1", "15:0:1", "20:0:2",
+		"100:1:0", "105:1:0", "110:1:1", "115:1:1", "120:1:2",
+		"200:2:0", "205:2:0", "210:2:1", "215:2:1", "220:2:2",
+	}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// no OCFs
+	mr, err = goavro.NewMultiOCFReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.Scan() {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+	if err = mr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = mr.Read()
+	ensureError(t, err, "Read called without successful Scan")
+}
+
+func TestMultiOCFReaderReaderSchema(t *testing.T) {
+	v1 := `{"type":"record","name":"r","fields":[{"name":"a","type":"int"},{"name":"c","type":"string"}]}`
+	v2 := `{"type":"record","name":"r","fields":[{"name":"a","type":"long"},{"name":"b","type":"string","default":"none"}]}`
+
+	mr, err := goavro.NewMultiOCFReaderWithConfig(goavro.MultiOCFReaderConfig{ReaderSchema: v2},
+		bytes.NewReader(newOCFOfRecords(t, v1, nil, map[string]interface{}{"a": 1, "c": "dropped"})),
+		bytes.NewReader(newOCFOfRecords(t, v2, nil, map[string]interface{}{"a": 2, "b": "two"})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data []interface{}
+	for mr.Scan() {
+		datum, err := mr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, datum)
+	}
+	if err = mr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"a": int64(1), "b": "none"},
+		map[string]interface{}{"a": int64(2), "b": "two"},
+	}
+	if actual := data; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// incompatible schema
+	mr, err = goavro.NewMultiOCFReaderWithConfig(goavro.MultiOCFReaderConfig{ReaderSchema: v2},
+		bytes.NewReader(newOCFOfRecords(t, `{"type":"record","name":"r","fields":[{"name":"b","type":"string"}]}`, nil, map[string]interface{}{"b": "no a"})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.Scan() {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+	ensureError(t, mr.Err(), "cannot resolve schema of OCF 0 to reader schema", "field \"a\"")
+
+	_, err = goavro.NewMultiOCFReaderWithConfig(goavro.MultiOCFReaderConfig{ReaderSchema: `{}`})
+	ensureError(t, err, "cannot create MultiOCFReader")
+}
+
+// readMultiOCFResolved returns the data items of an OCF written using the
+// writer schema, as read using the reader schema, or the first error.
+func readMultiOCFResolved(t *testing.T, writer, reader string, data ...interface{}) ([]interface{}, error) {
+	t.Helper()
+	mr, err := goavro.NewMultiOCFReaderWithConfig(goavro.MultiOCFReaderConfig{ReaderSchema: reader},
+		bytes.NewReader(newOCFOfRecords(t, writer, nil, data...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []interface{}
+	for mr.Scan() {
+		datum, err := mr.Read()
+		if err != nil {
+			return values, err
+		}
+		values = append(values, datum)
+	}
+	return values, mr.Err()
+}
+
+func TestMultiOCFReaderResolveUnions(t *testing.T) {
+	// writer value promoted into reader union
+	writer := `{"type":"record","name":"r","fields":[{"name":"a","type":"long"},{"name":"b","type":"int"}]}`
+	reader := `{"type":"record","name":"r","fields":[{"name":"a","type":["null","long"]},{"name":"b","type":["null","string","double"]}]}`
+	values, err := readMultiOCFResolved(t, writer, reader, map[string]interface{}{"a": 13, "b": 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{map[string]interface{}{
+		"a": map[string]interface{}{"long": int64(13)},
+		"b": map[string]interface{}{"double": float64(42)},
+	}}
+	if actual := values; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// writer union unwrapped into reader value, with null only an error when
+	// a data item uses it
+	writer = `{"type":"record","name":"r","fields":[{"name":"a","type":["null","int"]}]}`
+	reader = `{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`
+	values, err = readMultiOCFResolved(t, writer, reader,
+		map[string]interface{}{"a": goavro.Union("int", 3)},
+		map[string]interface{}{"a": nil},
+	)
+	ensureError(t, err, "cannot resolve datum from OCF 0 to reader schema", "union member \"null\"")
+	expected = []interface{}{map[string]interface{}{"a": int64(3)}}
+	if actual := values; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// writer union to reader union
+	writer = `["null","int","string"]`
+	reader = `["string","null","long"]`
+	values, err = readMultiOCFResolved(t, writer, reader, nil, goavro.Union("int", 1), goavro.Union("string", "two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []interface{}{nil, map[string]interface{}{"long": int64(1)}, map[string]interface{}{"string": "two"}}
+	if actual := values; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// no member of writer union resolves
+	_, err = readMultiOCFResolved(t, `["null","boolean"]`, `"long"`, nil)
+	ensureError(t, err, "cannot resolve schema of OCF 0 to reader schema", "any member of union")
+}
+
+func TestMultiOCFReaderResolveNames(t *testing.T) {
+	// names of records must match
+	writer := `{"type":"record","name":"r","namespace":"ns","fields":[{"name":"a","type":"long"}]}`
+	_, err := readMultiOCFResolved(t, writer, `{"type":"record","name":"other","fields":[{"name":"a","type":"long"}]}`,
+		map[string]interface{}{"a": 1},
+	)
+	ensureError(t, err, "cannot resolve schema of OCF 0 to reader schema", "names do not match")
+
+	// unqualified names match
+	values, err := readMultiOCFResolved(t, writer, `{"type":"record","name":"r","namespace":"other","fields":[{"name":"a","type":"long"}]}`,
+		map[string]interface{}{"a": 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := values, []interface{}{map[string]interface{}{"a": int64(1)}}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// record and field aliases
+	reader := `{"type":"record","name":"renamed","aliases":["ns.r"],"fields":[{"name":"b","aliases":["a"],"type":"long"}]}`
+	values, err = readMultiOCFResolved(t, writer, reader, map[string]interface{}{"a": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := values, []interface{}{map[string]interface{}{"b": int64(2)}}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// fixed size must match
+	_, err = readMultiOCFResolved(t, `{"type":"fixed","name":"f","size":2}`, `{"type":"fixed","name":"f","size":3}`, []byte("ab"))
+	ensureError(t, err, "cannot resolve fixed \"f\" of size 2 to fixed \"f\" of size 3")
+
+	// enum symbols only an error when a data item uses them
+	values, err = readMultiOCFResolved(t, `{"type":"enum","name":"e","symbols":["x","y","z"]}`, `{"type":"enum","name":"e","symbols":["y","x"]}`, "y", "z")
+	ensureError(t, err, "cannot resolve datum from OCF 0 to reader schema", "symbol \"z\"")
+	if actual, expected := values, []interface{}{"y"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestMultiOCFReaderResolveRecursive(t *testing.T) {
+	writer := `{"type":"record","name":"node","fields":[{"name":"value","type":"int"},{"name":"next","type":["null","node"]}]}`
+	reader := `{"type":"record","name":"node","fields":[{"name":"value","type":"long"},{"name":"next","type":["null","node"]},{"name":"label","type":"string","default":""}]}`
+	list := map[string]interface{}{"value": 1, "next": goavro.Union("node", map[string]interface{}{"value": 2, "next": nil})}
+	values, err := readMultiOCFResolved(t, writer, reader, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{map[string]interface{}{
+		"value": int64(1),
+		"label": "",
+		"next": map[string]interface{}{"node": map[string]interface{}{
+			"value": int64(2),
+			"label": "",
+			"next":  nil,
+		}},
+	}}
+	if actual := values; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestMultiOCFReaderGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for day := 1; day <= 4; day++ {
+		metadata := map[string][]byte{"day": []byte(fmt.Sprint(day))}
+		buf := newOCFOfRecords(t, `"long"`, metadata, int64(10*day), int64(10*day+1))
+		createTestFile(t, filepath.Join(dir, fmt.Sprintf("day-%d.avro", day)), buf)
+	}
+
+	// skip OCFs using their metadata
+	var pathnames []string
+	mr, err := goavro.NewMultiOCFReaderGlob(filepath.Join(dir, "day-*.avro"), goavro.MultiOCFReaderConfig{
+		SkipOCF: func(index int, pathname string, ocfr *goavro.OCFReader) bool {
+			day := string(ocfr.MetaData()["day"])
+			return day == "2" || day == "3"
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	var values []int64
+	for mr.Scan() {
+		datum, err := mr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, datum.(int64))
+		pathnames = append(pathnames, filepath.Base(mr.Pathname()))
+	}
+	if err = mr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := values, []int64{10, 11, 40, 41}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := pathnames, []string{"day-1.avro", "day-1.avro", "day-4.avro", "day-4.avro"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// error reading OCF identifies it
+	createTestFile(t, filepath.Join(dir, "day-5.avro"), []byte("not an OCF"))
+	mr, err = goavro.NewMultiOCFReaderGlob(filepath.Join(dir, "day-*.avro"), goavro.MultiOCFReaderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	var count int
+	for mr.Scan() {
+		if _, err = mr.Read(); err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if actual, expected := count, 8; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	ensureError(t, mr.Err(), "cannot read OCF", "day-5.avro", "cannot read OCF header")
+
+	// pattern matching no files
+	_, err = goavro.NewMultiOCFReaderGlob(filepath.Join(dir, "week-*.avro"), goavro.MultiOCFReaderConfig{})
+	ensureError(t, err, "cannot create MultiOCFReader when no files match pattern", "week-*.avro")
+}
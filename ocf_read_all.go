@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package goavro
+
+import (
+	"fmt"
+	"io"
+)
+
+// ReadAll reads every data item of the Avro Object Container File (OCF) read
+// from r, decoding each one directly into a value of type T, as described by
+// the ReadInto method of OCFReader. Because ReadInto is not supported by
+// readers with Concurrency greater than 1, ReadAll reads, decompresses, and
+// decodes blocks one at a time on the calling go routine.
+//
+//     people, err := goavro.ReadAll[Person](fh)
+//     if err != nil {
+//         return err
+//     }
+func ReadAll[T any](r io.Reader) ([]T, error) {
+	ocfr, err := NewOCFReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var values []T
+	for ocfr.Scan() {
+		var value T
+		if err = ocfr.ReadInto(&value); err != nil {
+			return nil, fmt.Errorf("cannot read data item %d: %s", len(values)+1, err)
+		}
+		values = append(values, value)
+	}
+	if err = ocfr.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,56 @@
+//go:build go1.18
+// +build go1.18
+
+package goavro_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+type quickstopPerson struct {
+	ID    int64
+	First string
+	Last  string
+	Phone string
+	Age   int
+}
+
+func TestReadAll(t *testing.T) {
+	schema := `{"type":"record","name":"Person","fields":[{"name":"ID","type":"long"},{"name":"First","type":"string"},{"name":"Age","type":"int"}]}`
+	buf := newOCFOfRecords(t, schema, nil,
+		map[string]interface{}{"ID": int64(1), "First": "Ada", "Age": int32(36)},
+		map[string]interface{}{"ID": int64(2), "First": "Alan", "Age": int32(41)},
+	)
+
+	people, err := goavro.ReadAll[quickstopPerson](bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := people, []quickstopPerson{{ID: 1, First: "Ada", Age: 36}, {ID: 2, First: "Alan", Age: 41}}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	ids, err := goavro.ReadAll[*struct{ ID int8 }](bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := len(ids), 2; actual != expected {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := ids[1].ID, int8(2); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	_, err = goavro.ReadAll[struct{ First int }](bytes.NewReader(buf))
+	ensureError(t, err, "cannot read data item 1", "cannot bind Avro string to Go int")
+
+	_, err = goavro.ReadAll[quickstopPerson](bytes.NewReader(buf[:len(buf)-1]))
+	ensureError(t, err, "sync marker")
+
+	_, err = goavro.ReadAll[quickstopPerson](bytes.NewReader([]byte("not an OCF")))
+	ensureError(t, err, "cannot create OCFReader")
+}
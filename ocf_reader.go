@@ -1,16 +1,11 @@
 package goavro
 
 import (
-	"bytes"
-	"compress/flate"
-	"encoding/binary"
+	"context"
 	"errors"
 	"fmt"
-	"hash/crc32"
 	"io"
-	"io/ioutil"
-
-	"github.com/golang/snappy"
+	"reflect"
 )
 
 // OCFReader structure is used to read Object Container Files (OCF).
@@ -22,6 +17,27 @@ type OCFReader struct {
 	ior                 io.Reader
 	readReady           bool  // true after Scan and before Read
 	remainingBlockItems int64 // count of encoded data items remaining in block buffer to be decoded
+
+	// Only used by readers created by NewOCFReaderWithConfig with Concurrency
+	// greater than 1.
+	pipeline *ocfPipeline
+
+	// Only used by ReadInto, which binds the schema to each Go type once.
+	bindings map[reflect.Type]bindingDecoder
+
+	// The following are only used by readers created by
+	// NewOCFReaderWithConfig with Recover.
+	rr          *resyncReader
+	corruptions []OCFCorruption // corrupt regions skipped so far
+	blockOffset int64           // offset of block being decoded
+	blockLength int64           // length of block being decoded
+
+	// The following are only used by readers created by NewOCFReaderAt.
+	ra        io.ReaderAt
+	or        *offsetReader // tracks offset of ior
+	size      int64         // size of OCF
+	end       int64         // blocks whose preceding sync marker begins at or after end are not read
+	dataStart int64         // offset of first block
 }
 
 // NewOCFReader initializes and returns a new structure used to read an Avro
@@ -60,16 +76,14 @@ func (ocfr *OCFReader) Codec() *Codec {
 // CompressionName returns the name of the compression algorithm found within
 // the OCF file.
 func (ocfr *OCFReader) CompressionName() string {
-	switch ocfr.header.compressionID {
-	case compressionNull:
-		return CompressionNullLabel
-	case compressionDeflate:
-		return CompressionDeflateLabel
-	case compressionSnappy:
-		return CompressionSnappyLabel
-	default:
-		return "should not get here: unrecognized compression algorithm"
-	}
+	return ocfr.header.compressionName
+}
+
+// MetaData returns the application specific metadata found within the OCF
+// file header, excluding the keys reserved by the Avro specification, such as
+// avro.schema and avro.codec. The returned map ought not be modified.
+func (ocfr *OCFReader) MetaData() map[string][]byte {
+	return ocfr.header.metadata
 }
 
 // Err returns the last error encountered while reading the OCF file.  See
@@ -82,6 +96,10 @@ func (ocfr *OCFReader) Err() error {
 // is designed to be called only once after each invocation of the Scan method.
 // See `NewOCFReader` documentation for an example.
 func (ocfr *OCFReader) Read() (interface{}, error) {
+	if ocfr.pipeline != nil {
+		return ocfr.readPipeline()
+	}
+
 	// NOTE: Test previous error before testing readReady to prevent overwriting
 	// previous error.
 	if ocfr.rerr != nil {
@@ -97,6 +115,14 @@ func (ocfr *OCFReader) Read() (interface{}, error) {
 	var datum interface{}
 	datum, ocfr.block, ocfr.rerr = ocfr.header.codec.NativeFromBinary(ocfr.block)
 	if ocfr.rerr != nil {
+		if ocfr.rr != nil {
+			// NOTE: When recovering from corruption, skip the remainder of
+			// the block rather than stopping.
+			err := ocfr.rerr
+			ocfr.rerr = nil
+			ocfr.skipCorruptBlock(ocfr.remainingBlockItems, err)
+			return nil, err
+		}
 		return false, ocfr.rerr
 	}
 	ocfr.remainingBlockItems--
@@ -115,6 +141,10 @@ func (ocfr *OCFReader) RemainingBlockItems() int64 {
 // time the Read method is invoked.  See `NewOCFReader` documentation for an
 // example.
 func (ocfr *OCFReader) Scan() bool {
+	if ocfr.pipeline != nil {
+		return ocfr.scanPipeline(context.Background())
+	}
+
 	ocfr.readReady = false
 
 	if ocfr.rerr != nil {
@@ -122,112 +152,168 @@ func (ocfr *OCFReader) Scan() bool {
 	}
 
 	// NOTE: If there are no more remaining data items from the existing block,
-	// then attempt to slurp in the next block.
-	if ocfr.remainingBlockItems <= 0 {
+	// then attempt to slurp in the next block. When recovering from
+	// corruption, corrupt blocks are skipped until a block has been read.
+	for ocfr.remainingBlockItems <= 0 {
 		if count := len(ocfr.block); count != 0 {
-			ocfr.rerr = fmt.Errorf("extra bytes between final datum in previous block and block sync marker: %d", count)
-			return false
+			err := fmt.Errorf("extra bytes between final datum in previous block and block sync marker: %d", count)
+			if ocfr.rr == nil {
+				ocfr.rerr = err
+				return false
+			}
+			ocfr.skipCorruptBlock(0, err)
 		}
 
-		// Read the block count and update the number of remaining items for
-		// this block
-		ocfr.remainingBlockItems, ocfr.rerr = longBinaryReader(ocfr.ior)
+		var count int64
+		var block []byte
+		count, block, ocfr.corruptions, ocfr.rerr = ocfr.readNextBlock(ocfr.corruptions)
 		if ocfr.rerr != nil {
 			if ocfr.rerr == io.EOF {
 				ocfr.rerr = nil // merely end of file, rather than error
-			} else {
-				ocfr.rerr = fmt.Errorf("cannot read block count: %s", ocfr.rerr)
 			}
 			return false
 		}
-		if ocfr.remainingBlockItems <= 0 {
-			ocfr.rerr = fmt.Errorf("cannot decode when block count is not greater than 0: %d", ocfr.remainingBlockItems)
-			return false
-		}
-		if ocfr.remainingBlockItems > MaxBlockCount {
-			ocfr.rerr = fmt.Errorf("cannot decode when block count exceeds MaxBlockCount: %d > %d", ocfr.remainingBlockItems, MaxBlockCount)
-		}
+		ocfr.remainingBlockItems = count
 
-		var blockSize int64
-		blockSize, ocfr.rerr = longBinaryReader(ocfr.ior)
-		if ocfr.rerr != nil {
-			ocfr.rerr = fmt.Errorf("cannot read block size: %s", ocfr.rerr)
-			return false
-		}
-		if blockSize <= 0 {
-			ocfr.rerr = fmt.Errorf("cannot decode when block size is not greater than 0: %d", blockSize)
-			return false
-		}
-		if blockSize > MaxBlockSize {
-			ocfr.rerr = fmt.Errorf("cannot decode when block size exceeds MaxBlockSize: %d > %d", blockSize, MaxBlockSize)
-			return false
+		var err error
+		if ocfr.block, err = ocfr.header.compressor.Decompress(nil, block); err != nil {
+			err = fmt.Errorf("cannot decompress: %s", err)
+			if ocfr.rr == nil {
+				ocfr.rerr = err
+				return false
+			}
+			ocfr.skipCorruptBlock(count, err)
 		}
+	}
 
-		// read entire block into buffer
-		ocfr.block = make([]byte, blockSize)
-		_, ocfr.rerr = io.ReadFull(ocfr.ior, ocfr.block)
-		if ocfr.rerr != nil {
-			ocfr.rerr = fmt.Errorf("cannot read block: %s", ocfr.rerr)
-			return false
-		}
+	ocfr.readReady = true
+	return true
+}
 
-		switch ocfr.header.compressionID {
-		case compressionNull:
-			// no-op
-
-		case compressionDeflate:
-			// NOTE: flate.NewReader wraps with io.ByteReader if argument does
-			// not implement that interface.
-			rc := flate.NewReader(bytes.NewBuffer(ocfr.block))
-			ocfr.block, ocfr.rerr = ioutil.ReadAll(rc)
-			if ocfr.rerr != nil {
-				_ = rc.Close()
-				return false
-			}
-			if ocfr.rerr = rc.Close(); ocfr.rerr != nil {
-				return false
-			}
+// OCFBlock is a block of data items read from, or to be written to, an OCF,
+// still encoded and compressed. Because the data items are not decoded, the
+// caller is responsible for ensuring a block is only written to an OCF having
+// the same schema as the OCF from which it was read.
+type OCFBlock struct {
+	// Count is the number of data items encoded in the block.
+	Count int64
 
-		case compressionSnappy:
-			index := len(ocfr.block) - 4 // last 4 bytes is crc32 of decoded block
-			if index <= 0 {
-				ocfr.rerr = fmt.Errorf("cannot decompress snappy without CRC32 checksum: %d", len(ocfr.block))
-				return false
-			}
-			decoded, err := snappy.Decode(nil, ocfr.block[:index])
-			if err != nil {
-				ocfr.rerr = fmt.Errorf("cannot decompress: %s", err)
-				return false
-			}
-			actualCRC := crc32.ChecksumIEEE(decoded)
-			expectedCRC := binary.BigEndian.Uint32(ocfr.block[index : index+4])
-			if actualCRC != expectedCRC {
-				ocfr.rerr = fmt.Errorf("snappy CRC32 checksum mismatch: %x != %x", actualCRC, expectedCRC)
-				return false
-			}
-			ocfr.block = decoded
+	// Data holds the encoded data items, compressed using the compression
+	// algorithm named by CompressionName.
+	Data []byte
 
-		default:
-			ocfr.rerr = fmt.Errorf("should not get here: cannot compress block using unrecognized compression: %d", ocfr.header.compressionID)
-			return false
+	// CompressionName is the name of the compression algorithm used to
+	// compress Data.
+	CompressionName string
+}
+
+// NextBlock returns the next block of the OCF without decompressing or
+// decoding its data items, or io.EOF when there are no more blocks. NextBlock
+// may be called in place of Scan, but only when all data items from any block
+// previously returned by Scan have been read. It is not supported by readers
+// created by NewOCFReaderWithConfig with Concurrency greater than 1.
+//
+//     func copyBlocks(ocfr *goavro.OCFReader, ocfw *goavro.OCFWriter) error {
+//         for {
+//             block, err := ocfr.NextBlock()
+//             if err == io.EOF {
+//                 return nil
+//             }
+//             if err != nil {
+//                 return err
+//             }
+//             if err = ocfw.AppendRawBlock(block); err != nil {
+//                 return err
+//             }
+//         }
+//     }
+func (ocfr *OCFReader) NextBlock() (OCFBlock, error) {
+	ocfr.readReady = false
 
+	if ocfr.rerr != nil {
+		return OCFBlock{}, ocfr.rerr
+	}
+	if ocfr.pipeline != nil {
+		return OCFBlock{}, errors.New("cannot read raw block from OCFReader with Concurrency greater than 1")
+	}
+	if ocfr.remainingBlockItems > 0 {
+		return OCFBlock{}, fmt.Errorf("cannot read raw block while data items remain in current block: %d", ocfr.remainingBlockItems)
+	}
+	if count := len(ocfr.block); count != 0 {
+		err := fmt.Errorf("extra bytes between final datum in previous block and block sync marker: %d", count)
+		if ocfr.rr == nil {
+			ocfr.rerr = err
+			return OCFBlock{}, ocfr.rerr
 		}
+		ocfr.skipCorruptBlock(0, err)
+	}
 
-		// read and ensure sync marker matches
-		sync := make([]byte, ocfSyncLength)
-		var n int
-		if n, ocfr.rerr = io.ReadFull(ocfr.ior, sync); ocfr.rerr != nil {
-			ocfr.rerr = fmt.Errorf("cannot read sync marker: read %d out of %d bytes: %s", n, ocfSyncLength, ocfr.rerr)
-			return false
+	var count int64
+	var data []byte
+	var err error
+	count, data, ocfr.corruptions, err = ocfr.readNextBlock(ocfr.corruptions)
+	if err != nil {
+		if err != io.EOF {
+			ocfr.rerr = err
 		}
-		if !bytes.Equal(sync, ocfr.header.syncMarker[:]) {
-			ocfr.rerr = fmt.Errorf("sync marker mismatch: %v != %v", sync, ocfr.header.syncMarker)
-			return false
+		return OCFBlock{}, err
+	}
+	return OCFBlock{Count: count, Data: data, CompressionName: ocfr.header.compressionName}, nil
+}
+
+// readBlock reads the next block from the underlying io.Reader, and returns
+// its count of data items and its compressed bytes. It returns io.EOF when
+// there are no more blocks to be read. When an error occurs after a valid
+// block count has been read, the block count is returned with the error.
+func (ocfr *OCFReader) readBlock() (int64, []byte, error) {
+	// When reading a byte range of the OCF, stop before the first block whose
+	// preceding sync marker begins at or after the end of the range.
+	if ocfr.or != nil && ocfr.or.offset-ocfSyncLength >= ocfr.end {
+		return 0, nil, io.EOF
+	}
+
+	// Read the block count
+	blockCount, err := longBinaryReader(ocfr.ior)
+	if err != nil {
+		if err == io.EOF {
+			return 0, nil, io.EOF // merely end of file, rather than error
 		}
+		return 0, nil, fmt.Errorf("cannot read block count: %s", err)
+	}
+	if blockCount <= 0 {
+		return 0, nil, fmt.Errorf("cannot decode when block count is not greater than 0: %d", blockCount)
+	}
+	if blockCount > MaxBlockCount {
+		return 0, nil, fmt.Errorf("cannot decode when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
 	}
 
-	ocfr.readReady = true
-	return true
+	blockSize, err := longBinaryReader(ocfr.ior)
+	if err != nil {
+		return blockCount, nil, fmt.Errorf("cannot read block size: %s", err)
+	}
+	if blockSize <= 0 {
+		return blockCount, nil, fmt.Errorf("cannot decode when block size is not greater than 0: %d", blockSize)
+	}
+	if blockSize > MaxBlockSize {
+		return blockCount, nil, fmt.Errorf("cannot decode when block size exceeds MaxBlockSize: %d > %d", blockSize, MaxBlockSize)
+	}
+
+	// read entire block into buffer
+	block := make([]byte, blockSize)
+	if _, err = io.ReadFull(ocfr.ior, block); err != nil {
+		return blockCount, nil, fmt.Errorf("cannot read block: %s", err)
+	}
+
+	// read and ensure sync marker matches
+	var sync [ocfSyncLength]byte
+	if n, err := io.ReadFull(ocfr.ior, sync[:]); err != nil {
+		return blockCount, nil, fmt.Errorf("cannot read sync marker: read %d out of %d bytes: %s", n, ocfSyncLength, err)
+	}
+	if sync != ocfr.header.syncMarker {
+		return blockCount, nil, fmt.Errorf("sync marker mismatch: %v != %v", sync[:], ocfr.header.syncMarker)
+	}
+
+	return blockCount, block, nil
 }
 
 // SkipThisBlockAndReset can be called after an error occurs while reading or
@@ -244,6 +330,10 @@ func (ocfr *OCFReader) Scan() bool {
 // start decoding datum values there.
 func (ocfr *OCFReader) SkipThisBlockAndReset() {
 	// ??? is it an error to call method unless the reader has had an error
+	if ocfr.pipeline != nil {
+		ocfr.skipPipelineBlock()
+		return
+	}
 	ocfr.remainingBlockItems = 0
 	ocfr.block = ocfr.block[:0]
 	ocfr.rerr = nil
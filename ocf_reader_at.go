@@ -0,0 +1,206 @@
+package goavro
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// offsetReader is a buffered reader over an io.ReaderAt that keeps track of
+// the offset of the next byte it will return.
+type offsetReader struct {
+	br     *bufio.Reader
+	offset int64
+}
+
+func newOffsetReader(ra io.ReaderAt, offset, size int64) *offsetReader {
+	return &offsetReader{br: bufio.NewReader(io.NewSectionReader(ra, offset, size-offset)), offset: offset}
+}
+
+func (or *offsetReader) Read(p []byte) (int, error) {
+	n, err := or.br.Read(p)
+	or.offset += int64(n)
+	return n, err
+}
+
+func (or *offsetReader) ReadByte() (byte, error) {
+	b, err := or.br.ReadByte()
+	if err == nil {
+		or.offset++
+	}
+	return b, err
+}
+
+// NewOCFReaderAt initializes and returns a new structure used to read an Avro
+// Object Container File (OCF) of size bytes from an io.ReaderAt, such as an
+// *os.File. Unlike a reader returned by NewOCFReader, the returned reader may
+// be repositioned using SeekToSyncAfter, and divided into independent readers,
+// each reading a different byte range of the OCF, using SplitRange or Split.
+//
+//     func example(fh *os.File) error {
+//         stat, err := fh.Stat()
+//         if err != nil {
+//             return err
+//         }
+//         ocfr, err := goavro.NewOCFReaderAt(fh, stat.Size())
+//         if err != nil {
+//             return err
+//         }
+//         splits, err := ocfr.Split(runtime.NumCPU())
+//         if err != nil {
+//             return err
+//         }
+//         var wg sync.WaitGroup
+//         wg.Add(len(splits))
+//         for _, split := range splits {
+//             go func(split *goavro.OCFReader) {
+//                 defer wg.Done()
+//                 for split.Scan() {
+//                     datum, err := split.Read()
+//                     // process datum and err
+//                 }
+//             }(split)
+//         }
+//         wg.Wait()
+//         return nil
+//     }
+func NewOCFReaderAt(ra io.ReaderAt, size int64) (*OCFReader, error) {
+	if ra == nil {
+		return nil, errors.New("cannot create OCFReader when io.ReaderAt is nil")
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("cannot create OCFReader when size is negative: %d", size)
+	}
+	or := newOffsetReader(ra, 0, size)
+	header, err := readOCFHeader(or)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OCFReader: %s", err)
+	}
+	return &OCFReader{
+		header:    header,
+		ior:       or,
+		ra:        ra,
+		or:        or,
+		size:      size,
+		end:       size,
+		dataStart: or.offset,
+	}, nil
+}
+
+// SeekToSyncAfter positions the reader at the first block whose preceding sync
+// marker begins at or after offset, in the same way Hadoop positions readers
+// at the start of an input split. Because the OCF header ends with a sync
+// marker, an offset at or before the end of the header positions the reader
+// at the first block. When no sync marker begins at or after offset, the
+// reader is positioned at the end of the OCF, and Scan will return false. Any
+// data items remaining in the current block are discarded, and any previous
+// error is cleared.
+//
+// This method returns an error when the reader was not created by
+// NewOCFReaderAt.
+func (ocfr *OCFReader) SeekToSyncAfter(offset int64) error {
+	if ocfr.ra == nil {
+		return errors.New("cannot seek OCFReader not created by NewOCFReaderAt")
+	}
+	position, err := ocfr.syncAfter(offset)
+	if err != nil {
+		return fmt.Errorf("cannot seek to sync marker after offset %d: %s", offset, err)
+	}
+	ocfr.or = newOffsetReader(ocfr.ra, position, ocfr.size)
+	ocfr.ior = ocfr.or
+	ocfr.block = nil
+	ocfr.rerr = nil
+	ocfr.derr = nil
+	ocfr.readReady = false
+	ocfr.remainingBlockItems = 0
+	return nil
+}
+
+// syncAfter returns the offset immediately following the first sync marker
+// that begins at or after offset, or the size of the OCF when there is no such
+// sync marker.
+func (ocfr *OCFReader) syncAfter(offset int64) (int64, error) {
+	if offset <= ocfr.dataStart-ocfSyncLength {
+		return ocfr.dataStart, nil
+	}
+
+	const chunkSize = 64 << 10
+	buf := make([]byte, chunkSize+ocfSyncLength-1)
+	marker := ocfr.header.syncMarker[:]
+
+	for offset+ocfSyncLength <= ocfr.size {
+		// NOTE: Each chunk overlaps the previous one by one byte less than the
+		// sync marker length, so a sync marker spanning two chunks is found.
+		n, err := ocfr.ra.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if remaining := ocfr.size - offset; int64(n) > remaining {
+			n = int(remaining) // ignore bytes beyond declared size
+		}
+		if index := bytes.Index(buf[:n], marker); index >= 0 {
+			return offset + int64(index) + ocfSyncLength, nil
+		}
+		if n < len(buf) {
+			break // read through end of OCF
+		}
+		offset += chunkSize
+	}
+	return ocfr.size, nil
+}
+
+// SplitRange returns a new OCFReader that reads only the blocks whose
+// preceding sync marker begins within the byte range [start, end). Every block
+// is read by exactly one of a set of readers whose ranges do not overlap and
+// together cover the OCF. The new reader shares the Codec and io.ReaderAt of
+// this reader, but is otherwise independent of it, so it may be used from a
+// different go routine.
+//
+// This method returns an error when the reader was not created by
+// NewOCFReaderAt.
+func (ocfr *OCFReader) SplitRange(start, end int64) (*OCFReader, error) {
+	if ocfr.ra == nil {
+		return nil, errors.New("cannot split OCFReader not created by NewOCFReaderAt")
+	}
+	if start > end {
+		return nil, fmt.Errorf("cannot split OCFReader when start is greater than end: %d > %d", start, end)
+	}
+	split := &OCFReader{
+		header:    ocfr.header,
+		ra:        ocfr.ra,
+		size:      ocfr.size,
+		end:       end,
+		dataStart: ocfr.dataStart,
+	}
+	if err := split.SeekToSyncAfter(start); err != nil {
+		return nil, err
+	}
+	return split, nil
+}
+
+// Split divides the OCF into n byte ranges of approximately equal size, and
+// returns a new OCFReader for each range as described by SplitRange. Every
+// block is read by exactly one of the returned readers, although some readers
+// will not read any blocks when n is large compared to the number of blocks in
+// the OCF.
+//
+// This method returns an error when the reader was not created by
+// NewOCFReaderAt.
+func (ocfr *OCFReader) Split(n int) ([]*OCFReader, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot split OCFReader when count is not greater than 0: %d", n)
+	}
+	splits := make([]*OCFReader, n)
+	for i := range splits {
+		start := ocfr.size * int64(i) / int64(n)
+		end := ocfr.size * int64(i+1) / int64(n)
+		split, err := ocfr.SplitRange(start, end)
+		if err != nil {
+			return nil, err
+		}
+		splits[i] = split
+	}
+	return splits, nil
+}
@@ -0,0 +1,213 @@
+package goavro_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+// newOCFOfLongs returns an OCF holding the long values 0 through count-1,
+// written in blocks of blockCount items.
+func newOCFOfLongs(t *testing.T, count, blockCount int, compressionName string) []byte {
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               bb,
+		Schema:          `"long"`,
+		CompressionName: compressionName,
+		BlockCount:      blockCount,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		if err = ocfw.Write(int64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+// scanOCFLongs returns the long values read by ocfr.
+func scanOCFLongs(t *testing.T, ocfr *goavro.OCFReader) []int64 {
+	var values []int64
+	for ocfr.Scan() {
+		value, err := ocfr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, value.(int64))
+	}
+	if err := ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestNewOCFReaderAt(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 7, goavro.CompressionNullLabel)
+
+	ocfr, err := goavro.NewOCFReaderAt(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := scanOCFLongs(t, ocfr)
+	if actual, expected := len(values), 100; actual != expected {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	for i, value := range values {
+		if actual, expected := value, int64(i); actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+
+	_, err = goavro.NewOCFReaderAt(nil, 0)
+	ensureError(t, err, "cannot create OCFReader", "nil")
+
+	_, err = goavro.NewOCFReaderAt(bytes.NewReader(buf), 10)
+	ensureError(t, err, "cannot create OCFReader")
+}
+
+func TestOCFReaderSeekToSyncAfter(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+
+	ocfr, err := goavro.NewOCFReaderAt(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// offset at start of OCF positions reader at first block
+	if err = ocfr.SeekToSyncAfter(0); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := len(scanOCFLongs(t, ocfr)), 100; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// offset in middle of OCF positions reader at start of a block
+	if err = ocfr.SeekToSyncAfter(int64(len(buf) / 2)); err != nil {
+		t.Fatal(err)
+	}
+	values := scanOCFLongs(t, ocfr)
+	if len(values) == 0 || len(values) >= 100 {
+		t.Fatalf("Actual: %v; Expected: some but not all values", len(values))
+	}
+	if actual, expected := values[0]%10, int64(0); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := values[len(values)-1], int64(99); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// offset at end of OCF positions reader at end
+	if err = ocfr.SeekToSyncAfter(int64(len(buf))); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := len(scanOCFLongs(t, ocfr)), 0; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// reader not created by NewOCFReaderAt cannot seek
+	ocfr, err = goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfr.SeekToSyncAfter(0), "cannot seek")
+	_, err = ocfr.Split(2)
+	ensureError(t, err, "cannot split")
+}
+
+func TestOCFReaderSplit(t *testing.T) {
+	for _, compressionName := range []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		buf := newOCFOfLongs(t, 1000, 7, compressionName)
+
+		ocfr, err := goavro.NewOCFReaderAt(bytes.NewReader(buf), int64(len(buf)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, n := range []int{1, 2, 3, 10, 64, 1000} {
+			splits, err := ocfr.Split(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := len(splits), n; actual != expected {
+				t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+			}
+
+			// every value read by exactly one split, and in order
+			var values []int64
+			for _, split := range splits {
+				values = append(values, scanOCFLongs(t, split)...)
+			}
+			if actual, expected := len(values), 1000; actual != expected {
+				t.Fatalf("%s; n: %d; Actual: %v; Expected: %v", compressionName, n, actual, expected)
+			}
+			for i, value := range values {
+				if actual, expected := value, int64(i); actual != expected {
+					t.Fatalf("%s; n: %d; Actual: %v; Expected: %v", compressionName, n, actual, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestOCFReaderSplitRange(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+	size := int64(len(buf))
+
+	ocfr, err := goavro.NewOCFReaderAt(bytes.NewReader(buf), size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// arbitrary split points still read every value exactly once
+	for _, middle := range []int64{1, 20, size / 3, size / 2, size - 17, size - 1} {
+		first, err := ocfr.SplitRange(0, middle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := ocfr.SplitRange(middle, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := append(scanOCFLongs(t, first), scanOCFLongs(t, second)...)
+		if actual, expected := len(values), 100; actual != expected {
+			t.Fatalf("middle: %d; Actual: %v; Expected: %v", middle, actual, expected)
+		}
+	}
+
+	_, err = ocfr.SplitRange(10, 5)
+	ensureError(t, err, "cannot split", "start is greater than end")
+
+	_, err = ocfr.Split(0)
+	ensureError(t, err, "cannot split", "not greater than 0")
+}
+
+func TestOCFReaderSplitLargerThanScanChunk(t *testing.T) {
+	buf := newOCFOfLongs(t, 200000, 997, goavro.CompressionNullLabel)
+
+	ocfr, err := goavro.NewOCFReaderAt(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	splits, err := ocfr.Split(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	for _, split := range splits {
+		for _, value := range scanOCFLongs(t, split) {
+			if actual, expected := value, int64(count); actual != expected {
+				t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+			}
+			count++
+		}
+	}
+	if actual, expected := count, 200000; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
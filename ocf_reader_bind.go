@@ -0,0 +1,103 @@
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ReadInto consumes one datum value from the Avro OCF stream and decodes it
+// directly into the value pointed to by v, without first decoding it to native
+// Go data. ReadInto may be called in place of Read, and is designed to be
+// called only once after each invocation of the Scan method. It is not
+// supported by readers created by NewOCFReaderWithConfig with Concurrency
+// greater than 1.
+//
+// The schema of the OCF is bound to the type of v the first time ReadInto is
+// called with a value of that type, and the binding is reused for every
+// subsequent data item. Avro records are typically decoded into Go structs,
+// whose exported fields are bound to the record fields having the name in
+// their avro struct tag, or, without a tag, having the same name, ignoring
+// case. Record fields without a corresponding struct field are skipped, and
+// struct fields without a corresponding record field are left unchanged.
+// Avro arrays are decoded into slices, maps into maps with string keys, enums
+// into strings or integers, fixed into byte arrays of the same size, and
+// unions with null into pointers. Any Avro type may be decoded into an empty
+// interface, in which case it is decoded as native Go data, as returned by
+// Read.
+//
+//     type Person struct {
+//         Name    string   `avro:"name"`
+//         Age     int      `avro:"age"`
+//         Email   *string  `avro:"email"` // ["null","string"]
+//         Friends []string `avro:"friends"`
+//     }
+//
+//     func example(ocfr *goavro.OCFReader) error {
+//         for ocfr.Scan() {
+//             var person Person
+//             if err := ocfr.ReadInto(&person); err != nil {
+//                 return err
+//             }
+//             fmt.Println(person)
+//         }
+//         return ocfr.Err()
+//     }
+func (ocfr *OCFReader) ReadInto(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot read into non-pointer or nil pointer: %T", v)
+	}
+	if ocfr.pipeline != nil {
+		return errors.New("cannot read into Go value from OCFReader with Concurrency greater than 1")
+	}
+	decoder, err := ocfr.binding(rv.Type().Elem())
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Test previous error before testing readReady to prevent overwriting
+	// previous error.
+	if ocfr.rerr != nil {
+		return ocfr.rerr
+	}
+	if !ocfr.readReady {
+		ocfr.rerr = errors.New("Read called without successful Scan")
+		return ocfr.rerr
+	}
+	ocfr.readReady = false
+
+	// decode one datum value from block
+	block, err := decoder(ocfr.block, rv.Elem())
+	if err != nil {
+		if ocfr.rr != nil {
+			// NOTE: When recovering from corruption, skip the remainder of
+			// the block rather than stopping.
+			ocfr.skipCorruptBlock(ocfr.remainingBlockItems, err)
+			return err
+		}
+		ocfr.rerr = err
+		return ocfr.rerr
+	}
+	ocfr.block = block
+	ocfr.remainingBlockItems--
+
+	return nil
+}
+
+// binding returns the decoder binding the schema of the OCF to t, binding it
+// the first time it is needed.
+func (ocfr *OCFReader) binding(t reflect.Type) (bindingDecoder, error) {
+	if decoder, ok := ocfr.bindings[t]; ok {
+		return decoder, nil
+	}
+	decoder, err := bindDecoder(ocfr.header.codec, t)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read into Go %s: %s", t, err)
+	}
+	if ocfr.bindings == nil {
+		ocfr.bindings = make(map[reflect.Type]bindingDecoder)
+	}
+	ocfr.bindings[t] = decoder
+	return decoder, nil
+}
@@ -0,0 +1,231 @@
+package goavro_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+const bindSchema = `{"type":"record","name":"Reading","namespace":"com.example","fields":[
+	{"name":"id","type":"long"},
+	{"name":"station","type":"string"},
+	{"name":"count","type":"int"},
+	{"name":"temperature","type":"float"},
+	{"name":"pressure","type":"double"},
+	{"name":"valid","type":"boolean"},
+	{"name":"raw","type":"bytes"},
+	{"name":"digest","type":{"type":"fixed","name":"md5","size":4}},
+	{"name":"status","type":{"type":"enum","name":"Status","symbols":["OK","WARN","FAIL"]}},
+	{"name":"level","type":"Status"},
+	{"name":"tags","type":{"type":"array","items":"string"}},
+	{"name":"attributes","type":{"type":"map","values":"long"}},
+	{"name":"note","type":["null","string"]},
+	{"name":"previous","type":["null","Reading"]},
+	{"name":"extra","type":["null","long","string"]},
+	{"name":"ignored","type":{"type":"array","items":"double"}}
+]}`
+
+type bindReading struct {
+	ID          int64
+	Station     string
+	Count       int16 `avro:"count"`
+	Temperature float64
+	Pressure    float64
+	Valid       bool
+	Raw         []byte
+	Digest      [4]byte
+	Status      string
+	Level       int
+	Tags        []string
+	Attributes  map[string]int
+	Note        *string
+	Previous    *bindReading
+	Extra       interface{}
+	Ignored     []float64 `avro:"-"`
+	Local       string
+}
+
+func bindReadingNative(id int64, previous interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          id,
+		"station":     "north",
+		"count":       int32(3),
+		"temperature": float32(21.5),
+		"pressure":    1013.25,
+		"valid":       true,
+		"raw":         []byte("raw"),
+		"digest":      []byte{1, 2, 3, 4},
+		"status":      "WARN",
+		"level":       "FAIL",
+		"tags":        []interface{}{"a", "b"},
+		"attributes":  map[string]interface{}{"x": int64(1), "y": int64(-2)},
+		"note":        nil,
+		"previous":    previous,
+		"extra":       goavro.Union("string", "more"),
+		"ignored":     []interface{}{1.5},
+	}
+}
+
+func TestOCFReaderReadInto(t *testing.T) {
+	note := "noted"
+	first := bindReadingNative(1, nil)
+	second := bindReadingNative(2, goavro.Union("com.example.Reading", first))
+	second["note"] = goavro.Union("string", note)
+	second["extra"] = goavro.Union("long", int64(7))
+	second["tags"] = []interface{}{}
+	buf := newOCFOfRecords(t, bindSchema, nil, first, second)
+
+	expectedFirst := bindReading{
+		ID:          1,
+		Station:     "north",
+		Count:       3,
+		Temperature: 21.5,
+		Pressure:    1013.25,
+		Valid:       true,
+		Raw:         []byte("raw"),
+		Digest:      [4]byte{1, 2, 3, 4},
+		Status:      "WARN",
+		Level:       2,
+		Tags:        []string{"a", "b"},
+		Attributes:  map[string]int{"x": 1, "y": -2},
+		Extra:       map[string]interface{}{"string": "more"},
+		Local:       "untouched",
+	}
+	expectedSecond := expectedFirst
+	expectedSecond.ID = 2
+	expectedSecond.Note = &note
+	expectedSecond.Extra = map[string]interface{}{"long": int64(7)}
+	expectedSecond.Tags = []string{}
+	expectedPrevious := expectedFirst
+	expectedPrevious.Local = ""
+	expectedSecond.Previous = &expectedPrevious
+
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readings []bindReading
+	for ocfr.Scan() {
+		reading := bindReading{Local: "untouched"}
+		if err = ocfr.ReadInto(&reading); err != nil {
+			t.Fatal(err)
+		}
+		readings = append(readings, reading)
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := readings, []bindReading{expectedFirst, expectedSecond}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %+v; Expected: %+v", actual, expected)
+	}
+}
+
+func TestOCFReaderReadIntoInterface(t *testing.T) {
+	// decoding into an empty interface matches Read
+	for _, pathname := range []string{"fixtures/quickstop-null.avro", "fixtures/weather-null.avro"} {
+		expected := readOCFFixture(t, pathname, goavro.CompressionNullLabel)
+		avroBlob, err := ioutil.ReadFile(pathname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ocfr, err := goavro.NewOCFReader(bytes.NewReader(avroBlob))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []interface{}
+		for ocfr.Scan() {
+			var datum interface{}
+			if err = ocfr.ReadInto(&datum); err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, datum)
+		}
+		if err = ocfr.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: Actual: %v; Expected: %v", pathname, actual, expected)
+		}
+	}
+}
+
+func TestOCFReaderReadIntoEmptyRecord(t *testing.T) {
+	schema := `{"type":"record","name":"r1","fields":[{"name":"id","type":"long"},{"name":"marker","type":{"type":"record","name":"empty","fields":[]}}]}`
+	buf := newOCFOfRecords(t, schema, nil,
+		map[string]interface{}{"id": int64(1), "marker": map[string]interface{}{}},
+		map[string]interface{}{"id": int64(2), "marker": map[string]interface{}{}},
+	)
+
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for ocfr.Scan() {
+		var datum struct {
+			ID     int64    `avro:"id"`
+			Marker struct{} `avro:"marker"`
+		}
+		if err = ocfr.ReadInto(&datum); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, datum.ID)
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := ids, []int64{1, 2}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestOCFReaderReadIntoErrors(t *testing.T) {
+	buf := newOCFOfRecords(t, `{"type":"record","name":"r","fields":[{"name":"a","type":"long"},{"name":"b","type":["null","string"]}]}`, nil,
+		map[string]interface{}{"a": int64(300), "b": nil})
+
+	newReader := func() *goavro.OCFReader {
+		ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ocfr.Scan() {
+			t.Fatal(ocfr.Err())
+		}
+		return ocfr
+	}
+
+	ocfr := newReader()
+	var value struct{ A int64 }
+	ensureError(t, ocfr.ReadInto(value), "cannot read into non-pointer")
+
+	// binding errors do not consume the data item
+	var wrongType struct{ A string }
+	ensureError(t, ocfr.ReadInto(&wrongType), "cannot read into Go", `record "r" field "a"`, "cannot bind Avro long to Go string")
+	var wrongUnion struct{ B *int }
+	ensureError(t, ocfr.ReadInto(&wrongUnion), "cannot bind Avro string to Go int")
+	if err := ocfr.ReadInto(&value); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := value.A, int64(300); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	ensureError(t, ocfr.ReadInto(&value), "Read called without successful Scan")
+
+	var small struct{ A int8 }
+	ensureError(t, newReader().ReadInto(&small), `record "r" field "a"`, "overflows Go int8: 300")
+	var unsigned struct{ A uint8 }
+	ensureError(t, newReader().ReadInto(&unsigned), "overflows Go uint8: 300")
+
+	ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ocfr.Close()
+	if !ocfr.Scan() {
+		t.Fatal(ocfr.Err())
+	}
+	ensureError(t, ocfr.ReadInto(&value), "cannot read into Go value from OCFReader with Concurrency greater than 1")
+}
@@ -0,0 +1,97 @@
+package goavro
+
+import (
+	"context"
+	"io"
+)
+
+// contextReaderChunkSize is the maximum number of bytes a contextReader reads
+// from its underlying io.Reader between checks of its context, so that reading
+// a large block may be cancelled part way through.
+const contextReaderChunkSize = 64 << 10
+
+// contextReader is an io.Reader which returns the error of its context once
+// the context is done.
+type contextReader struct {
+	ctx context.Context
+	ior io.Reader
+	err error // error of ctx, once returned by Read or ReadByte
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		cr.err = err
+		return 0, err
+	}
+	if len(p) > contextReaderChunkSize {
+		p = p[:contextReaderChunkSize]
+	}
+	return cr.ior.Read(p)
+}
+
+func (cr *contextReader) ReadByte() (byte, error) {
+	if err := cr.ctx.Err(); err != nil {
+		cr.err = err
+		return 0, err
+	}
+	if br, ok := cr.ior.(io.ByteReader); ok {
+		return br.ReadByte()
+	}
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(cr.ior, buf); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// ScanContext is like Scan, but returns false once ctx is done, including
+// part way through reading a block from the underlying io.Reader, after which
+// Err returns the error of ctx, and the reader may not be used to read more
+// data items.
+func (ocfr *OCFReader) ScanContext(ctx context.Context) bool {
+	if err := ctx.Err(); err != nil {
+		ocfr.readReady = false
+		if ocfr.rerr == nil {
+			ocfr.rerr = err
+		}
+		if ocfr.pipeline != nil {
+			ocfr.pipeline.cancel()
+		}
+		return false
+	}
+	if ctx.Done() == nil {
+		return ocfr.Scan() // context can never be cancelled
+	}
+	if ocfr.pipeline != nil {
+		return ocfr.scanPipeline(ctx)
+	}
+
+	ior := ocfr.ior
+	cr := &contextReader{ctx: ctx, ior: ior}
+	ocfr.ior = cr
+	ok := ocfr.Scan()
+	ocfr.ior = ior
+
+	// NOTE: Report the error of ctx in place of the error it caused while
+	// reading, but never in place of any other error.
+	if err := ctx.Err(); !ok && err != nil && (ocfr.rerr == nil || cr.err != nil) {
+		ocfr.rerr = err
+	}
+	return ok
+}
+
+// ReadContext is like Read, but returns the error of ctx once ctx is done,
+// after which Err also returns it.
+func (ocfr *OCFReader) ReadContext(ctx context.Context) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		ocfr.readReady = false
+		if ocfr.rerr == nil {
+			ocfr.rerr = err
+		}
+		if ocfr.pipeline != nil {
+			ocfr.pipeline.cancel()
+		}
+		return nil, ocfr.rerr
+	}
+	return ocfr.Read()
+}
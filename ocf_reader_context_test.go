@@ -0,0 +1,162 @@
+package goavro_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/karrick/goavro"
+)
+
+func TestOCFReaderScanContext(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionDeflateLabel)
+
+	for _, concurrency := range []int{0, 3} {
+		ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: concurrency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var count int
+		for ocfr.ScanContext(ctx) {
+			if _, err = ocfr.ReadContext(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if count++; count == 25 {
+				cancel() // stops between records
+			}
+		}
+		if actual, expected := count, 25; actual != expected {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+		if actual, expected := ocfr.Err(), context.Canceled; actual != expected {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+		if ocfr.ScanContext(context.Background()) {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, true, false)
+		}
+		if err = ocfr.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOCFReaderReadContext(t *testing.T) {
+	buf := newOCFOfLongs(t, 10, 10, goavro.CompressionNullLabel)
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if !ocfr.ScanContext(ctx) {
+		t.Fatal(ocfr.Err())
+	}
+	cancel()
+	_, err = ocfr.ReadContext(ctx)
+	if actual, expected := err, context.Canceled; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := ocfr.Err(), context.Canceled; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// cancellingReader cancels a context once more than limit bytes have been
+// read from it.
+type cancellingReader struct {
+	r      *bytes.Reader
+	limit  int
+	read   int
+	cancel context.CancelFunc
+}
+
+func (cr *cancellingReader) Read(p []byte) (int, error) {
+	if len(p) > 1024 {
+		p = p[:1024]
+	}
+	n, err := cr.r.Read(p)
+	if cr.read += n; cr.read > cr.limit {
+		cr.cancel()
+	}
+	return n, err
+}
+
+func TestOCFReaderScanContextWithinBlock(t *testing.T) {
+	// single block holding about 1 MiB
+	buf := newOCFOfLongs(t, 200000, 200000, goavro.CompressionNullLabel)
+	if len(buf) < 1<<19 {
+		t.Fatalf("Actual: %v; Expected: at least %v", len(buf), 1<<19)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cr := &cancellingReader{r: bytes.NewReader(buf), limit: 4096, cancel: cancel}
+	ocfr, err := goavro.NewOCFReader(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ocfr.ScanContext(ctx) {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+	if actual, expected := ocfr.Err(), context.Canceled; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if cr.read >= len(buf)/2 {
+		t.Errorf("Actual: %v; Expected: less than %v", cr.read, len(buf)/2)
+	}
+}
+
+func TestOCFReaderScanContextWithinBlockRecover(t *testing.T) {
+	buf := newOCFOfLongs(t, 200000, 50000, goavro.CompressionNullLabel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cr := &cancellingReader{r: bytes.NewReader(buf), limit: 4096, cancel: cancel}
+	ocfr, err := goavro.NewOCFReaderWithConfig(cr, goavro.OCFReaderConfig{Recover: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	go func() { done <- ocfr.ScanContext(ctx) }()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Errorf("Actual: %v; Expected: %v", ok, false)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("ScanContext did not return after context cancelled")
+	}
+	if actual, expected := ocfr.Err(), context.Canceled; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := len(ocfr.Corruptions()), 0; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// TestOCFReaderScanContextKeepsError ensures an error reading the OCF is not
+// replaced by the error of the context, when the context is done only after
+// the error occurred.
+func TestOCFReaderScanContextKeepsError(t *testing.T) {
+	buf := newOCFOfLongs(t, 10, 10, goavro.CompressionNullLabel)
+	buf[len(buf)-1] ^= 0xff // corrupt final sync marker
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cr := &cancellingReader{r: bytes.NewReader(buf), limit: len(buf) - 1, cancel: cancel} // cancel once final byte read
+	ocfr, err := goavro.NewOCFReader(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ocfr.ScanContext(ctx) {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+	if ctx.Err() == nil {
+		t.Fatalf("Actual: %v; Expected: %v", ctx.Err(), context.Canceled)
+	}
+	ensureError(t, ocfr.Err(), "sync marker mismatch")
+}
@@ -0,0 +1,45 @@
+//go:build go1.23
+// +build go1.23
+
+package goavro
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns an iterator over the data items of the OCF, yielding each data
+// item along with any error returned by Read, then any error returned by Err
+// once there are no more data items.
+//
+//     func example(ocfr *goavro.OCFReader) error {
+//         for datum, err := range ocfr.All() {
+//             if err != nil {
+//                 return err
+//             }
+//             fmt.Println(datum)
+//         }
+//         return nil
+//     }
+func (ocfr *OCFReader) All() iter.Seq2[any, error] {
+	return ocfr.AllContext(context.Background())
+}
+
+// AllContext is like All, but stops once ctx is done, in the same way as
+// ScanContext, yielding the error of ctx.
+func (ocfr *OCFReader) AllContext(ctx context.Context) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		for ocfr.ScanContext(ctx) {
+			datum, err := ocfr.Read()
+			if !yield(datum, err) {
+				return
+			}
+			if err != nil && ocfr.Err() != nil {
+				return // error already yielded
+			}
+		}
+		if err := ocfr.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
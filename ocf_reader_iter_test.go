@@ -0,0 +1,124 @@
+//go:build go1.23
+// +build go1.23
+
+package goavro_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+func TestOCFReaderAll(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionSnappyLabel)
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []int64
+	for datum, err := range ocfr.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, datum.(int64))
+	}
+	if actual, expected := values, expectedLongs(100); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// break stops iteration, and reader may continue
+	ocfr, err = goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for datum := range ocfr.All() {
+		if datum.(int64) == 4 {
+			break
+		}
+	}
+	if actual, expected := scanOCFLongs(t, ocfr), expectedLongs(100, 0, 5); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestOCFReaderAllErrors(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+	syncs := ocfSyncOffsets(buf)
+	buf[syncs[3]-1] |= 0x80 // final datum of block 2 continues past end of block
+	buf[syncs[6]+3] ^= 0xff // sync marker following block 5 mismatch
+
+	// error is yielded once, then iteration stops
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count, errs int
+	for _, err := range ocfr.All() {
+		if err != nil {
+			ensureError(t, err, "short buffer")
+			errs++
+			continue
+		}
+		count++
+	}
+	if actual, expected := count, 29; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := errs, 1; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// when recovering from corruption, iteration continues after errors
+	ocfr, err = goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Recover: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, errs = 0, 0
+	for _, err := range ocfr.All() {
+		if err != nil {
+			errs++
+			continue
+		}
+		count++
+	}
+	if actual, expected := count, 79; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := errs, 1; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := len(ocfr.Corruptions()), 2; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestOCFReaderAllContext(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var count int
+	var last error
+	for datum, err := range ocfr.AllContext(ctx) {
+		if err != nil {
+			last = err
+			continue
+		}
+		if datum.(int64) == 41 {
+			cancel()
+		}
+		count++
+	}
+	if actual, expected := count, 42; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := last, context.Canceled; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
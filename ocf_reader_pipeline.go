@@ -0,0 +1,445 @@
+package goavro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// OCFReaderConfig is used to specify creation parameters for an OCFReader
+// created by NewOCFReaderWithConfig.
+type OCFReaderConfig struct {
+	// Concurrency specifies the number of go routines used to decompress and
+	// decode blocks, (optional). If omitted, 0, or 1, blocks are read,
+	// decompressed, and decoded by Scan and Read, in the same way as a reader
+	// created by NewOCFReader.
+	Concurrency int
+
+	// PrefetchBlocks specifies the maximum number of blocks read from the
+	// underlying io.Reader ahead of the block being consumed by Scan and Read,
+	// (optional). If omitted, or 0, defaults to twice Concurrency.
+	PrefetchBlocks int
+
+	// MaxBufferedBytes specifies the maximum number of bytes of blocks read
+	// from the underlying io.Reader, but not yet consumed by Scan and Read,
+	// (optional), counting both the compressed and decompressed size of each
+	// block, the latter approximating the memory used by its decoded data
+	// items. If omitted, or 0, only PrefetchBlocks limits how far ahead blocks
+	// are read and decoded. Blocks are not read while the budget is exhausted,
+	// and a worker which has decompressed a block waits before decoding it,
+	// so up to one decompressed block per worker may be held beyond the
+	// budget. A single block larger than MaxBufferedBytes is still read and
+	// decoded when it is the next block to be consumed.
+	MaxBufferedBytes int
+
+	// Recover specifies whether the reader recovers from corrupt blocks,
+	// (optional). If true, when a block cannot be read, decompressed, or
+	// decoded, rather than stopping, the reader skips it, and resumes reading
+	// at the block following the next sync marker. Each region skipped is
+	// reported by the Corruptions method. When a data item cannot be decoded,
+	// Read returns the error, and the following Scan resumes at the next
+	// block.
+	Recover bool
+}
+
+// ocfPipelineBlock is a block read by the pipeline, along with its data items
+// once decompressed and decoded by a worker.
+type ocfPipelineBlock struct {
+	count int64
+	seq   int64         // sequence number of block, used by memory budget
+	raw   []byte        // compressed block, released once decoded
+	size  int           // length of raw, plus length of decompressed block once decoded
+	done  chan struct{} // closed once data, derr, and serr are set
+	data  []interface{} // successfully decoded data items
+	derr  error         // decode error following the decoded data items
+	serr  error         // block error, reported by Scan after the data items
+
+	// The following are only used when recovering from corruption.
+	corrupt     bool            // serr describes a corrupt block, rather than a read error
+	offset      int64           // offset of block
+	length      int64           // length of block
+	corruptions []OCFCorruption // corrupt regions skipped before block
+}
+
+// ocfPipeline reads blocks from the underlying io.Reader in one go routine,
+// and decompresses and decodes them in a pool of worker go routines. Blocks
+// are delivered to the consumer in their original order using the blocks
+// channel, each once its done channel has been closed.
+type ocfPipeline struct {
+	blocks chan *ocfPipelineBlock // ordered blocks, for consumer
+	jobs   chan *ocfPipelineBlock // blocks to be decoded, for workers
+	quit   <-chan struct{}        // closed to cancel pipeline
+	stop   context.CancelFunc     // cancels pipeline, including reads by producer
+	once   sync.Once
+	wg     sync.WaitGroup // workers, but not producer, which may be blocked reading
+
+	// memory budget
+	lock        sync.Mutex
+	cond        *sync.Cond
+	buffered    int   // bytes of blocks read but not yet consumed
+	next        int64 // sequence number of next block to be consumed
+	maxBuffered int
+	closed      bool
+
+	// consumer state
+	current *ocfPipelineBlock
+	index   int // index of next data item of current block to return
+}
+
+// NewOCFReaderWithConfig initializes and returns a new structure used to read
+// an Avro Object Container File (OCF), using the specified configuration. When
+// Concurrency is greater than 1, blocks are read from the underlying io.Reader
+// by a separate go routine, then decompressed and decoded by a pool of worker
+// go routines, while data items are still returned by Scan and Read in their
+// original order. Such a reader must be released by calling its Close method
+// when no longer needed, which also cancels any outstanding work when the
+// OCF has not been read to its end.
+//
+//     func example(ior io.Reader) error {
+//         ocfr, err := goavro.NewOCFReaderWithConfig(bufio.NewReader(ior), goavro.OCFReaderConfig{
+//             Concurrency: runtime.NumCPU(),
+//         })
+//         if err != nil {
+//             return err
+//         }
+//         defer ocfr.Close()
+//         for ocfr.Scan() {
+//             datum, err := ocfr.Read()
+//             if err != nil {
+//                 return err
+//             }
+//             fmt.Println(datum)
+//         }
+//         return ocfr.Err()
+//     }
+func NewOCFReaderWithConfig(ior io.Reader, config OCFReaderConfig) (*OCFReader, error) {
+	if config.Concurrency < 0 {
+		return nil, fmt.Errorf("cannot create OCFReader when Concurrency is negative: %d", config.Concurrency)
+	}
+	if config.PrefetchBlocks < 0 {
+		return nil, fmt.Errorf("cannot create OCFReader when PrefetchBlocks is negative: %d", config.PrefetchBlocks)
+	}
+	if config.MaxBufferedBytes < 0 {
+		return nil, fmt.Errorf("cannot create OCFReader when MaxBufferedBytes is negative: %d", config.MaxBufferedBytes)
+	}
+	var rr *resyncReader
+	if config.Recover {
+		rr = newResyncReader(ior)
+		ior = rr
+	}
+	ocfr, err := NewOCFReader(ior)
+	if err != nil {
+		return nil, err
+	}
+	ocfr.rr = rr
+	if config.Concurrency > 1 {
+		ocfr.startPipeline(config)
+	}
+	return ocfr, nil
+}
+
+// Close releases the go routines used by a reader created by
+// NewOCFReaderWithConfig, cancelling any outstanding work. After Close, Scan
+// returns false. Close has no effect for other readers, and always returns
+// nil.
+//
+// Close does not wait for a call to the Read method of the underlying
+// io.Reader which is in progress, such as when reading from a stalled network
+// connection. The go routine reading blocks exits once that call returns,
+// without reading more, so to abort the call itself, close the underlying
+// io.Reader after calling Close.
+func (ocfr *OCFReader) Close() error {
+	p := ocfr.pipeline
+	if p == nil {
+		return nil
+	}
+	p.cancel()
+	p.wg.Wait()
+	ocfr.readReady = false
+	p.current = nil
+	return nil
+}
+
+func (ocfr *OCFReader) startPipeline(config OCFReaderConfig) {
+	prefetch := config.PrefetchBlocks
+	if prefetch == 0 {
+		prefetch = 2 * config.Concurrency
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	p := &ocfPipeline{
+		blocks:      make(chan *ocfPipelineBlock, prefetch),
+		jobs:        make(chan *ocfPipelineBlock, prefetch),
+		quit:        ctx.Done(),
+		stop:        stop,
+		maxBuffered: config.MaxBufferedBytes,
+	}
+	p.cond = sync.NewCond(&p.lock)
+	ocfr.pipeline = p
+
+	// NOTE: Only the producer reads from the underlying io.Reader, and it
+	// stops part way through reading a block once the pipeline is cancelled.
+	ocfr.ior = &contextReader{ctx: ctx, ior: ocfr.ior}
+
+	p.wg.Add(config.Concurrency)
+	go ocfr.pipelineProducer()
+	for i := 0; i < config.Concurrency; i++ {
+		go ocfr.pipelineWorker()
+	}
+}
+
+// cancel stops the producer and workers, without waiting for them.
+func (p *ocfPipeline) cancel() {
+	p.once.Do(func() {
+		p.stop()
+		p.lock.Lock()
+		p.closed = true
+		p.cond.Broadcast()
+		p.lock.Unlock()
+	})
+}
+
+// pipelineProducer reads blocks from the underlying io.Reader, and sends each
+// block to both the consumer and the workers, until there are no more blocks,
+// or the pipeline is cancelled.
+func (ocfr *OCFReader) pipelineProducer() {
+	p := ocfr.pipeline
+	defer close(p.jobs)
+	defer close(p.blocks)
+
+	for seq := int64(0); ; seq++ {
+		count, raw, corruptions, err := ocfr.readNextBlock(nil)
+		if err == io.EOF {
+			if len(corruptions) > 0 {
+				// NOTE: Deliver regions skipped at end of OCF using an empty
+				// block.
+				b := &ocfPipelineBlock{seq: seq, corruptions: corruptions, done: make(chan struct{})}
+				close(b.done)
+				select {
+				case p.blocks <- b:
+				case <-p.quit:
+				}
+			}
+			return
+		}
+		b := &ocfPipelineBlock{count: count, seq: seq, raw: raw, size: len(raw), done: make(chan struct{}), corruptions: corruptions}
+		if err != nil {
+			b.count = 0
+			b.serr = err
+			close(b.done)
+			select {
+			case p.blocks <- b:
+			case <-p.quit:
+			}
+			return
+		}
+		b.offset, b.length = ocfr.blockOffset, ocfr.blockLength
+		if !p.acquire(b.seq, b.size) {
+			return
+		}
+		select {
+		case p.blocks <- b:
+		case <-p.quit:
+			return
+		}
+		select {
+		case p.jobs <- b:
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// pipelineWorker decompresses and decodes blocks until there are no more
+// blocks, or the pipeline is cancelled.
+func (ocfr *OCFReader) pipelineWorker() {
+	p := ocfr.pipeline
+	defer p.wg.Done()
+
+	for {
+		select {
+		case b, ok := <-p.jobs:
+			if !ok {
+				return
+			}
+			ocfr.decodeBlock(b)
+			close(b.done)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// decodeBlock decompresses the block, then decodes its data items once its
+// decompressed size fits within the memory budget.
+func (ocfr *OCFReader) decodeBlock(b *ocfPipelineBlock) {
+	p := ocfr.pipeline
+	buf, err := ocfr.header.compressor.Decompress(nil, b.raw)
+	b.raw = nil
+	if err != nil {
+		b.serr = fmt.Errorf("cannot decompress: %s", err)
+		b.corrupt = true
+		return
+	}
+	if !p.acquire(b.seq, len(buf)) {
+		return // cancelled
+	}
+	b.size += len(buf)
+	// NOTE: Do not trust the block count of a corrupt block when allocating
+	// the data items. Most data items use at least one byte, and any using
+	// none are appended to the slice as it grows.
+	capacity := b.count
+	if capacity > int64(len(buf)) {
+		capacity = int64(len(buf))
+	}
+	b.data = make([]interface{}, 0, capacity)
+	for i := int64(0); i < b.count; i++ {
+		var datum interface{}
+		if datum, buf, err = ocfr.header.codec.NativeFromBinary(buf); err != nil {
+			b.derr = err
+			return
+		}
+		b.data = append(b.data, datum)
+	}
+	if count := len(buf); count != 0 {
+		b.serr = fmt.Errorf("extra bytes between final datum in previous block and block sync marker: %d", count)
+		b.corrupt = true
+	}
+}
+
+// acquire waits until size bytes for block seq fit within the memory budget,
+// or block seq is the next block to be consumed, then adds them to the
+// buffered bytes. It returns false when the pipeline has been cancelled.
+//
+// NOTE: Never waiting for the next block to be consumed ensures the consumer
+// can always release bytes, because workers receive blocks in order, so a
+// worker waiting for one block never prevents an earlier block from being
+// decoded.
+func (p *ocfPipeline) acquire(seq int64, size int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for !p.closed && p.maxBuffered > 0 && seq != p.next && p.buffered+size > p.maxBuffered {
+		p.cond.Wait()
+	}
+	p.buffered += size
+	return !p.closed
+}
+
+// release removes the bytes of the consumed block b from the buffered bytes.
+func (p *ocfPipeline) release(b *ocfPipelineBlock) {
+	p.lock.Lock()
+	p.buffered -= b.size
+	p.next = b.seq + 1
+	p.cond.Broadcast()
+	p.lock.Unlock()
+}
+
+// scanPipeline is the Scan method for a reader using a pipeline. It returns
+// false once ctx is done, after which the pipeline is cancelled, because the
+// reader may not be used to read more data items.
+func (ocfr *OCFReader) scanPipeline(ctx context.Context) bool {
+	p := ocfr.pipeline
+	ocfr.readReady = false
+
+	if ocfr.rerr != nil {
+		return false
+	}
+	select {
+	case <-p.quit:
+		return false // closed
+	default:
+	}
+
+	for {
+		if b := p.current; b != nil {
+			if p.index < len(b.data) || (p.index == len(b.data) && b.derr != nil) {
+				ocfr.readReady = true
+				return true
+			}
+			if b.serr != nil {
+				if ocfr.rr == nil || !b.corrupt {
+					ocfr.rerr = b.serr
+					return false
+				}
+				ocfr.corruptions = append(ocfr.corruptions, OCFCorruption{Offset: b.offset, Length: b.length, Items: b.count - int64(len(b.data)), Err: b.serr})
+				b.serr = nil
+			}
+			p.release(b)
+			p.current = nil
+		}
+
+		var b *ocfPipelineBlock
+		var ok bool
+		select {
+		case b, ok = <-p.blocks:
+		case <-p.quit:
+		case <-ctx.Done():
+			ocfr.rerr = ctx.Err()
+			p.cancel()
+			return false
+		}
+		if !ok {
+			return false // end of OCF, or pipeline cancelled
+		}
+		select {
+		case <-b.done:
+		case <-p.quit:
+			return false
+		case <-ctx.Done():
+			ocfr.rerr = ctx.Err()
+			p.cancel()
+			return false
+		}
+		ocfr.corruptions = append(ocfr.corruptions, b.corruptions...)
+		p.current = b
+		p.index = 0
+		ocfr.remainingBlockItems = b.count
+	}
+}
+
+// readPipeline is the Read method for a reader using a pipeline.
+func (ocfr *OCFReader) readPipeline() (interface{}, error) {
+	p := ocfr.pipeline
+	if ocfr.rerr != nil {
+		return nil, ocfr.rerr
+	}
+	if !ocfr.readReady {
+		ocfr.rerr = errors.New("Read called without successful Scan")
+		return nil, ocfr.rerr
+	}
+	ocfr.readReady = false
+
+	b := p.current
+	if p.index < len(b.data) {
+		datum := b.data[p.index]
+		b.data[p.index] = nil // allow datum to be released once caller done with it
+		p.index++
+		ocfr.remainingBlockItems--
+		return datum, nil
+	}
+	if ocfr.rr != nil {
+		// NOTE: When recovering from corruption, skip the remainder of the
+		// block rather than stopping.
+		err := b.derr
+		ocfr.corruptions = append(ocfr.corruptions, OCFCorruption{Offset: b.offset, Length: b.length, Items: b.count - int64(len(b.data)), Err: err})
+		b.derr = nil
+		ocfr.remainingBlockItems = 0
+		return nil, err
+	}
+	ocfr.rerr = b.derr
+	return nil, ocfr.rerr
+}
+
+// skipPipelineBlock is the SkipThisBlockAndReset method for a reader using a
+// pipeline.
+func (ocfr *OCFReader) skipPipelineBlock() {
+	p := ocfr.pipeline
+	if b := p.current; b != nil {
+		p.index = len(b.data)
+		b.derr = nil
+		b.serr = nil
+	}
+	ocfr.remainingBlockItems = 0
+	ocfr.rerr = nil
+}
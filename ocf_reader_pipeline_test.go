@@ -0,0 +1,326 @@
+package goavro_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karrick/goavro"
+)
+
+func TestOCFReaderConcurrency(t *testing.T) {
+	for _, compressionName := range []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		buf := newOCFOfLongs(t, 10000, 37, compressionName)
+
+		for _, config := range []goavro.OCFReaderConfig{
+			{Concurrency: 2},
+			{Concurrency: 4, PrefetchBlocks: 1},
+			{Concurrency: 8, PrefetchBlocks: 100},
+			{Concurrency: 4, MaxBufferedBytes: 1}, // every block exceeds budget
+			{Concurrency: 4, MaxBufferedBytes: 256},
+		} {
+			ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := scanOCFLongs(t, ocfr)
+			if err = ocfr.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := len(values), 10000; actual != expected {
+				t.Fatalf("%s; %+v; Actual: %v; Expected: %v", compressionName, config, actual, expected)
+			}
+			for i, value := range values {
+				if actual, expected := value, int64(i); actual != expected {
+					t.Fatalf("%s; %+v; Actual: %v; Expected: %v", compressionName, config, actual, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestOCFReaderConcurrencyRemainingBlockItems(t *testing.T) {
+	buf := newOCFOfLongs(t, 10, 4, goavro.CompressionNullLabel)
+
+	ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ocfr.Close()
+
+	var remaining []int64
+	for ocfr.Scan() {
+		remaining = append(remaining, ocfr.RemainingBlockItems())
+		if _, err = ocfr.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := remaining, []int64{4, 3, 2, 1, 4, 3, 2, 1, 2, 1}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// readOCFFirstError returns the number of data items read from the OCF in buf
+// using the specified configuration before the first error, along with that
+// error.
+func readOCFFirstError(t *testing.T, buf []byte, config goavro.OCFReaderConfig) (int, error) {
+	ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), config)
+	if err != nil {
+		return 0, err
+	}
+	defer ocfr.Close()
+
+	var count int
+	for ocfr.Scan() {
+		if _, err = ocfr.Read(); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, ocfr.Err()
+}
+
+// TestOCFReaderConcurrencyErrors ensures a pipelined reader reports the same
+// data items and error as a serial reader for every fixture.
+func TestOCFReaderConcurrencyErrors(t *testing.T) {
+	pathnames, err := filepath.Glob("fixtures/*.avro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pathname := range pathnames {
+		buf, err := ioutil.ReadFile(pathname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedCount, expectedErr := readOCFFirstError(t, buf, goavro.OCFReaderConfig{})
+		actualCount, actualErr := readOCFFirstError(t, buf, goavro.OCFReaderConfig{Concurrency: 3})
+
+		if actual, expected := actualCount, expectedCount; actual != expected {
+			t.Errorf("%s: Actual: %v; Expected: %v", pathname, actual, expected)
+		}
+		if actual, expected := actualErr, expectedErr; (actual == nil) != (expected == nil) || (actual != nil && actual.Error() != expected.Error()) {
+			t.Errorf("%s: Actual: %v; Expected: %v", pathname, actual, expected)
+		}
+	}
+}
+
+func TestOCFReaderConcurrencyClose(t *testing.T) {
+	buf := newOCFOfLongs(t, 10000, 10, goavro.CompressionDeflateLabel)
+
+	ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: 4, PrefetchBlocks: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 25; i++ {
+		if !ocfr.Scan() {
+			t.Fatal(ocfr.Err())
+		}
+		if _, err = ocfr.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close cancels outstanding work, and returns once go routines exit
+	if err = ocfr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ocfr.Scan() {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfr.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stallingReader returns bytes from r until limit bytes have been read, after
+// which its Read method blocks until unblock is closed, like a stalled network
+// connection.
+type stallingReader struct {
+	r       *bytes.Reader
+	limit   int
+	read    int
+	unblock chan struct{}
+}
+
+func (sr *stallingReader) Read(p []byte) (int, error) {
+	if sr.read >= sr.limit {
+		<-sr.unblock
+		return 0, io.ErrClosedPipe
+	}
+	if remaining := sr.limit - sr.read; len(p) > remaining {
+		p = p[:remaining]
+	}
+	n, err := sr.r.Read(p)
+	sr.read += n
+	return n, err
+}
+
+func TestOCFReaderConcurrencyCloseWhileReading(t *testing.T) {
+	buf := newOCFOfLongs(t, 10000, 10, goavro.CompressionNullLabel)
+	sr := &stallingReader{r: bytes.NewReader(buf), limit: 200, unblock: make(chan struct{})} // header and a few blocks
+	defer close(sr.unblock)
+
+	ocfr, err := goavro.NewOCFReaderWithConfig(sr, goavro.OCFReaderConfig{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ocfr.Scan() {
+		t.Fatal(ocfr.Err())
+	}
+
+	done := make(chan error)
+	go func() { done <- ocfr.Close() }()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Close did not return while underlying reader blocked")
+	}
+	if ocfr.Scan() {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+}
+
+// TestOCFReaderConcurrencyBudgetDecompressed ensures the memory budget counts
+// decompressed blocks, so blocks which compress well are not decoded far ahead
+// of the consumer.
+func TestOCFReaderConcurrencyBudgetDecompressed(t *testing.T) {
+	const blocks, size = 40, 1 << 20
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               bb,
+		Schema:          `"string"`,
+		CompressionName: goavro.CompressionDeflateLabel,
+		BlockCount:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	datum := strings.Repeat("a", size)
+	for i := 0; i < blocks; i++ {
+		if err = ocfw.Write(datum); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(bb.Bytes()), goavro.OCFReaderConfig{
+		Concurrency:      4,
+		PrefetchBlocks:   blocks,
+		MaxBufferedBytes: 4 * size,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ocfr.Close()
+	if !ocfr.Scan() {
+		t.Fatal(ocfr.Err())
+	}
+	time.Sleep(200 * time.Millisecond) // allow pipeline to read ahead
+
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	// budget, plus one decompressed block per worker, plus slack
+	if actual, limit := stats.HeapAlloc, uint64(20*size); actual > limit {
+		t.Errorf("Actual: %v; Expected: at most %v", actual, limit)
+	}
+
+	var count int
+	for ; ocfr.Scan(); count++ {
+		if _, err = ocfr.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := count, blocks; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// TestOCFReaderConcurrencyScanContextCancel ensures the go routines of the
+// pipeline stop once the context provided to ScanContext is done, without
+// waiting for Close.
+func TestOCFReaderConcurrencyScanContextCancel(t *testing.T) {
+	buf := newOCFOfLongs(t, 10000, 10, goavro.CompressionNullLabel)
+	before := runtime.NumGoroutine()
+
+	ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ocfr.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	if !ocfr.ScanContext(ctx) {
+		t.Fatal(ocfr.Err())
+	}
+	cancel()
+	if ocfr.ScanContext(ctx) {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+	ensureError(t, ocfr.Err(), "context canceled")
+
+	for deadline := time.Now().Add(10 * time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Actual: %v; Expected: at most %v", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOCFReaderConcurrencyCorruptBlockCount(t *testing.T) {
+	// header followed by block claiming many more items than it holds
+	buf := newOCFOfLongs(t, 0, 1, goavro.CompressionNullLabel)
+	syncMarker := append([]byte(nil), buf[len(buf)-16:]...)
+	var b [binary.MaxVarintLen64]byte
+	buf = append(buf, b[:binary.PutVarint(b[:], 1<<24)]...) // block count
+	buf = append(buf, b[:binary.PutVarint(b[:], 1)]...)     // block size
+	buf = append(buf, 2)                                    // long 1
+	buf = append(buf, syncMarker...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	count, err := readOCFFirstError(t, buf, goavro.OCFReaderConfig{Concurrency: 2})
+	runtime.ReadMemStats(&after)
+
+	if actual, expected := count, 1; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	ensureError(t, err, "short buffer")
+	if actual, limit := after.TotalAlloc-before.TotalAlloc, uint64(16<<20); actual > limit {
+		t.Errorf("Actual: %v; Expected: at most %v", actual, limit)
+	}
+}
+
+func TestOCFReaderConcurrencyInvalidConfig(t *testing.T) {
+	buf := newOCFOfLongs(t, 1, 1, goavro.CompressionNullLabel)
+
+	_, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: -1})
+	ensureError(t, err, "cannot create OCFReader", "Concurrency")
+
+	_, err = goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{PrefetchBlocks: -1})
+	ensureError(t, err, "cannot create OCFReader", "PrefetchBlocks")
+
+	_, err = goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{MaxBufferedBytes: -1})
+	ensureError(t, err, "cannot create OCFReader", "MaxBufferedBytes")
+}
@@ -0,0 +1,171 @@
+package goavro
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// OCFCorruption describes a corrupt region of an OCF skipped by a reader
+// created by NewOCFReaderWithConfig with Recover.
+type OCFCorruption struct {
+	// Offset is the offset of the first byte skipped, from the start of the
+	// OCF.
+	Offset int64
+
+	// Length is the number of bytes skipped, from the start of the corrupt
+	// block to the start of the block at which reading resumed.
+	Length int64
+
+	// Items is the number of data items lost, as declared by the corrupt
+	// block, or 0 when its block count could not be read. Because a corrupt
+	// region may span more than one block, this is a lower bound.
+	Items int64
+
+	// Err is the error that caused the region to be skipped.
+	Err error
+}
+
+// Corruptions returns the corrupt regions of the OCF skipped so far by a
+// reader created by NewOCFReaderWithConfig with Recover, in the order they
+// were skipped. It returns nil for other readers.
+func (ocfr *OCFReader) Corruptions() []OCFCorruption {
+	return ocfr.corruptions
+}
+
+// resyncReader is a buffered reader used by an OCFReader recovering from
+// corruption. It keeps the bytes returned since the start of the block being
+// read, so that when the block turns out to be corrupt, the reader can be
+// resynchronized on the first sync marker following the start of the block,
+// even when that sync marker was already consumed while reading the block.
+type resyncReader struct {
+	br      *bufio.Reader
+	pending []byte // bytes to be returned before reading more from br
+	kept    []byte // bytes returned since start
+	start   int64  // offset of start of block being read
+	offset  int64  // offset of next byte to be returned
+}
+
+func newResyncReader(ior io.Reader) *resyncReader {
+	return &resyncReader{br: bufio.NewReader(ior)}
+}
+
+func (rr *resyncReader) Read(p []byte) (int, error) {
+	var n int
+	var err error
+	if len(rr.pending) > 0 {
+		n = copy(p, rr.pending)
+		rr.pending = rr.pending[n:]
+	} else {
+		n, err = rr.br.Read(p)
+	}
+	rr.kept = append(rr.kept, p[:n]...)
+	rr.offset += int64(n)
+	return n, err
+}
+
+func (rr *resyncReader) ReadByte() (byte, error) {
+	var b byte
+	if len(rr.pending) > 0 {
+		b = rr.pending[0]
+		rr.pending = rr.pending[1:]
+	} else {
+		var err error
+		if b, err = rr.br.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	rr.kept = append(rr.kept, b)
+	rr.offset++
+	return b, nil
+}
+
+// mark records the current offset as the start of the next block.
+func (rr *resyncReader) mark() {
+	rr.kept = rr.kept[:0]
+	rr.start = rr.offset
+}
+
+// resync positions the reader immediately after the first occurrence of
+// marker that begins after the start of the block being read, or at the end
+// of the underlying io.Reader when there is no such occurrence.
+func (rr *resyncReader) resync(marker []byte) error {
+	// NOTE: Search the bytes already returned, except the first byte of the
+	// block, followed by any bytes not yet returned.
+	buf := make([]byte, 0, len(rr.kept)+len(rr.pending))
+	if len(rr.kept) > 0 {
+		buf = append(buf, rr.kept[1:]...)
+	}
+	buf = append(buf, rr.pending...)
+	offset := rr.start + 1 // offset of buf[0]
+	rr.kept = rr.kept[:0]
+	rr.pending = nil
+
+	chunk := make([]byte, 64<<10)
+	for {
+		if index := bytes.Index(buf, marker); index >= 0 {
+			rr.pending = buf[index+len(marker):]
+			rr.offset = offset + int64(index+len(marker))
+			return nil
+		}
+		// NOTE: Keep one byte less than the sync marker length, so a sync
+		// marker spanning two reads is found.
+		if keep := len(marker) - 1; len(buf) > keep {
+			offset += int64(len(buf) - keep)
+			buf = append(buf[:0], buf[len(buf)-keep:]...)
+		}
+		n, err := rr.br.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if err == io.EOF {
+			if bytes.Index(buf, marker) >= 0 {
+				continue
+			}
+			rr.offset = offset + int64(len(buf))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readNextBlock reads the next block like readBlock. When the reader was
+// created with Recover, any corrupt blocks are skipped by resynchronizing on
+// the sync marker following the start of each, and a description of each
+// skipped region is appended to corruptions, which is returned.
+func (ocfr *OCFReader) readNextBlock(corruptions []OCFCorruption) (int64, []byte, []OCFCorruption, error) {
+	rr := ocfr.rr
+	for {
+		if rr != nil {
+			rr.mark()
+		}
+		count, block, err := ocfr.readBlock()
+		if rr == nil || err == io.EOF {
+			return count, block, corruptions, err
+		}
+		if err == nil {
+			ocfr.blockOffset, ocfr.blockLength = rr.start, rr.offset-rr.start
+			return count, block, corruptions, nil
+		}
+		// NOTE: A block that could not be read because the context of
+		// ScanContext is done is not corrupt, and resynchronizing would read
+		// the remainder of the stream only to fail again.
+		if cr, ok := ocfr.ior.(*contextReader); ok && cr.ctx.Err() != nil {
+			return 0, nil, corruptions, cr.ctx.Err()
+		}
+		start := rr.start
+		if rerr := rr.resync(ocfr.header.syncMarker[:]); rerr != nil {
+			return 0, nil, corruptions, rerr
+		}
+		corruptions = append(corruptions, OCFCorruption{Offset: start, Length: rr.offset - start, Items: count, Err: err})
+	}
+}
+
+// skipCorruptBlock records the block most recently read by readNextBlock as
+// corrupt, having lost items data items, and discards any of its remaining
+// data items.
+func (ocfr *OCFReader) skipCorruptBlock(items int64, err error) {
+	ocfr.corruptions = append(ocfr.corruptions, OCFCorruption{Offset: ocfr.blockOffset, Length: ocfr.blockLength, Items: items, Err: err})
+	ocfr.remainingBlockItems = 0
+	ocfr.block = nil
+}
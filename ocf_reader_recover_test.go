@@ -0,0 +1,170 @@
+package goavro_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+// ocfSyncOffsets returns the offset of each occurrence of the sync marker of
+// the OCF in buf, the first of which terminates its header.
+func ocfSyncOffsets(buf []byte) []int {
+	marker := buf[len(buf)-16:]
+	var offsets []int
+	for offset := 0; ; {
+		index := bytes.Index(buf[offset:], marker)
+		if index < 0 {
+			return offsets
+		}
+		offsets = append(offsets, offset+index)
+		offset += index + 16
+	}
+}
+
+// recoverOCFLongs returns the long values read from the OCF in buf by a reader
+// recovering from corruption, along with the corrupt regions it skipped, and
+// the number of errors returned by Read.
+func recoverOCFLongs(t *testing.T, buf []byte, concurrency int) ([]int64, []goavro.OCFCorruption, int) {
+	ocfr, err := goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: concurrency, Recover: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ocfr.Close()
+
+	var values []int64
+	var readErrors int
+	for ocfr.Scan() {
+		value, err := ocfr.Read()
+		if err != nil {
+			readErrors++
+			continue
+		}
+		values = append(values, value.(int64))
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values, ocfr.Corruptions(), readErrors
+}
+
+// expectedLongs returns the long values 0 through count-1, except those in
+// the half open ranges specified by pairs of values in lost.
+func expectedLongs(count int, lost ...int) []int64 {
+	var values []int64
+	for i := 0; i < count; i++ {
+		var skip bool
+		for j := 0; j < len(lost); j += 2 {
+			if i >= lost[j] && i < lost[j+1] {
+				skip = true
+			}
+		}
+		if !skip {
+			values = append(values, int64(i))
+		}
+	}
+	return values
+}
+
+func testOCFReaderRecover(t *testing.T, buf []byte, expectedValues []int64, expectedCorruptions []goavro.OCFCorruption, expectedReadErrors int, errorSubstring string) {
+	t.Helper()
+	for _, concurrency := range []int{0, 3} {
+		values, corruptions, readErrors := recoverOCFLongs(t, buf, concurrency)
+		if actual, expected := fmt.Sprint(values), fmt.Sprint(expectedValues); actual != expected {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+		if actual, expected := readErrors, expectedReadErrors; actual != expected {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+		if actual, expected := len(corruptions), len(expectedCorruptions); actual != expected {
+			t.Fatalf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+		for i, corruption := range corruptions {
+			ensureError(t, corruption.Err, errorSubstring)
+			corruption.Err = nil
+			if actual, expected := corruption, expectedCorruptions[i]; actual != expected {
+				t.Errorf("Concurrency: %d; Actual: %+v; Expected: %+v", concurrency, actual, expected)
+			}
+		}
+	}
+}
+
+func TestOCFReaderRecoverSyncMarkerMismatch(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+	syncs := ocfSyncOffsets(buf)
+
+	// corrupt sync marker following block 3, which loses blocks 3 and 4
+	buf[syncs[4]+7] ^= 0xff
+
+	_, err := readOCFFirstError(t, buf, goavro.OCFReaderConfig{})
+	ensureError(t, err, "sync marker mismatch")
+
+	testOCFReaderRecover(t, buf, expectedLongs(100, 30, 50), []goavro.OCFCorruption{
+		{Offset: int64(syncs[3] + 16), Length: int64(syncs[5] - syncs[3]), Items: 10},
+	}, 0, "sync marker mismatch")
+}
+
+func TestOCFReaderRecoverDecompress(t *testing.T) {
+	for _, compressionName := range []string{goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		buf := newOCFOfLongs(t, 100, 10, compressionName)
+		syncs := ocfSyncOffsets(buf)
+
+		// corrupt final byte of block 6, which is the final byte of the
+		// snappy checksum, or of the deflate stream
+		buf[syncs[7]-1] ^= 0xff
+
+		_, err := readOCFFirstError(t, buf, goavro.OCFReaderConfig{})
+		ensureError(t, err, "cannot decompress")
+
+		testOCFReaderRecover(t, buf, expectedLongs(100, 60, 70), []goavro.OCFCorruption{
+			{Offset: int64(syncs[6] + 16), Length: int64(syncs[7] - syncs[6]), Items: 10},
+		}, 0, "cannot decompress")
+	}
+}
+
+func TestOCFReaderRecoverTruncated(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionDeflateLabel)
+	syncs := ocfSyncOffsets(buf)
+
+	// truncate in middle of final block
+	truncated := buf[:syncs[9]+30]
+
+	testOCFReaderRecover(t, truncated, expectedLongs(100, 90, 100), []goavro.OCFCorruption{
+		{Offset: int64(syncs[9] + 16), Length: 14, Items: 10},
+	}, 0, "cannot read block")
+}
+
+func TestOCFReaderRecoverInsertedBytes(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+	syncs := ocfSyncOffsets(buf)
+
+	// insert garbage following sync marker after block 1, which loses block
+	// 2, whose block count cannot be read
+	garbage := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	corrupted := append(append(append([]byte(nil), buf[:syncs[2]+16]...), garbage...), buf[syncs[2]+16:]...)
+
+	testOCFReaderRecover(t, corrupted, expectedLongs(100, 20, 30), []goavro.OCFCorruption{
+		{Offset: int64(syncs[2] + 16), Length: int64(syncs[3] - syncs[2] + len(garbage))},
+	}, 0, "block count")
+}
+
+func TestOCFReaderRecoverDecode(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+	syncs := ocfSyncOffsets(buf)
+
+	// make final datum of block 2 continue past end of block
+	buf[syncs[3]-1] |= 0x80
+
+	_, err := readOCFFirstError(t, buf, goavro.OCFReaderConfig{})
+	ensureError(t, err, "short buffer")
+
+	testOCFReaderRecover(t, buf, expectedLongs(100, 29, 30), []goavro.OCFCorruption{
+		{Offset: int64(syncs[2] + 16), Length: int64(syncs[3] - syncs[2]), Items: 1},
+	}, 1, "short buffer")
+}
+
+func TestOCFReaderRecoverNoCorruption(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionSnappyLabel)
+	testOCFReaderRecover(t, buf, expectedLongs(100), nil, 0, "")
+}
@@ -2,6 +2,8 @@ package goavro_test
 
 import (
 	"bytes"
+	"os"
+	"reflect"
 	"testing"
 
 	"github.com/karrick/goavro"
@@ -92,3 +94,52 @@ func TestReadOCFHeaderMetadataSyncMarker(t *testing.T) {
 // func TestOCFReaderRead(t *testing.T) {
 // 	testOCFReader(t,
 // }
+
+// readOCFFixture returns all data items stored in the specified fixture file.
+func readOCFFixture(t *testing.T, pathname, expectedCompressionName string) []interface{} {
+	fh, err := os.Open(pathname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = fh.Close() }()
+
+	ocfr, err := goavro.NewOCFReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := ocfr.CompressionName(), expectedCompressionName; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	var data []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, datum)
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestOCFReaderCompressionFixtures(t *testing.T) {
+	expected := readOCFFixture(t, "fixtures/weather-null.avro", goavro.CompressionNullLabel)
+	if len(expected) == 0 {
+		t.Fatal("Actual: no data items; Expected: some data items")
+	}
+
+	for _, compressionName := range []string{
+		goavro.CompressionDeflateLabel,
+		goavro.CompressionZstandardLabel,
+		goavro.CompressionBzip2Label,
+		goavro.CompressionXZLabel,
+	} {
+		actual := readOCFFixture(t, "fixtures/weather-"+compressionName+".avro", compressionName)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: Actual: %v; Expected: %v", compressionName, actual, expected)
+		}
+	}
+}
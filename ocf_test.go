@@ -2,6 +2,9 @@ package goavro_test
 
 import (
 	"bytes"
+	"compress/flate"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/karrick/goavro"
@@ -10,13 +13,20 @@ import (
 // testOCFRoundTrip has OCFWriter write to a buffer using specified
 // compression algorithm, then attempt to read it back
 func testOCFRoundTrip(t *testing.T, compressionName string) {
+	testOCFRoundTripWithLevel(t, compressionName, 0)
+}
+
+// testOCFRoundTripWithLevel has OCFWriter write to a buffer using specified
+// compression algorithm and compression level, then attempt to read it back
+func testOCFRoundTripWithLevel(t *testing.T, compressionName string, compressionLevel int) {
 	schema := `{"type":"long"}`
 
 	bb := new(bytes.Buffer)
 	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
-		W:               bb,
-		CompressionName: compressionName,
-		Schema:          schema,
+		W:                bb,
+		CompressionName:  compressionName,
+		CompressionLevel: compressionLevel,
+		Schema:           schema,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -64,6 +74,129 @@ func TestOCFWriterCompressionDeflate(t *testing.T) {
 	testOCFRoundTrip(t, goavro.CompressionDeflateLabel)
 }
 
+func TestOCFWriterCompressionDeflateLevels(t *testing.T) {
+	for _, level := range []int{flate.HuffmanOnly, flate.DefaultCompression, flate.BestSpeed, flate.BestCompression} {
+		testOCFRoundTripWithLevel(t, goavro.CompressionDeflateLabel, level)
+	}
+}
+
+// TestOCFWriterReusesBuffers ensures blocks appended after larger blocks are
+// not corrupted by the contents of reused working buffers.
+func TestOCFWriterReusesBuffers(t *testing.T) {
+	for _, compressionName := range []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		bb := new(bytes.Buffer)
+		ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+			W:               bb,
+			CompressionName: compressionName,
+			Schema:          `{"type":"string"}`,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		valuesToWrite := []interface{}{strings.Repeat("long value ", 100), "short", "", strings.Repeat("x", 1000), "y"}
+		for _, value := range valuesToWrite {
+			if err = ocfw.Append([]interface{}{value}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ocfr, err := goavro.NewOCFReader(bb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var valuesRead []interface{}
+		for ocfr.Scan() {
+			value, err := ocfr.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			valuesRead = append(valuesRead, value)
+		}
+		if err = ocfr.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := valuesRead, valuesToWrite; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: Actual: %v; Expected: %v", compressionName, actual, expected)
+		}
+	}
+}
+
 func TestOCFWriterCompressionSnappy(t *testing.T) {
 	testOCFRoundTrip(t, goavro.CompressionSnappyLabel)
 }
+
+func TestOCFWriterCompressionZstandard(t *testing.T) {
+	testOCFRoundTrip(t, goavro.CompressionZstandardLabel)
+	testOCFRoundTripWithLevel(t, goavro.CompressionZstandardLabel, 1)
+	testOCFRoundTripWithLevel(t, goavro.CompressionZstandardLabel, 19)
+}
+
+func TestOCFWriterCompressionBzip2(t *testing.T) {
+	testOCFRoundTrip(t, goavro.CompressionBzip2Label)
+	testOCFRoundTripWithLevel(t, goavro.CompressionBzip2Label, 1)
+	testOCFRoundTripWithLevel(t, goavro.CompressionBzip2Label, 9)
+}
+
+func TestOCFWriterCompressionXZ(t *testing.T) {
+	testOCFRoundTrip(t, goavro.CompressionXZLabel)
+	testOCFRoundTripWithLevel(t, goavro.CompressionXZLabel, 1)
+	testOCFRoundTripWithLevel(t, goavro.CompressionXZLabel, 9)
+}
+
+func TestOCFWriterCompressionLevelInvalid(t *testing.T) {
+	testLevel := func(compressionName string, compressionLevel int, expected ...string) {
+		_, err := goavro.NewOCFWriter(goavro.OCFConfig{
+			W:                new(bytes.Buffer),
+			CompressionName:  compressionName,
+			CompressionLevel: compressionLevel,
+			Schema:           `{"type":"long"}`,
+		})
+		ensureError(t, err, expected...)
+	}
+	testLevel(goavro.CompressionNullLabel, 1, "cannot use compression level with compression codec")
+	testLevel(goavro.CompressionDeflateLabel, 10, "outside range")
+	testLevel(goavro.CompressionSnappyLabel, 1, "cannot use compression level with compression codec")
+	testLevel(goavro.CompressionZstandardLabel, 23, "outside range")
+	testLevel(goavro.CompressionBzip2Label, -1, "outside range")
+	testLevel(goavro.CompressionXZLabel, 10, "outside range")
+}
+
+// xorCompressor is a Compressor used to test registering compression codecs.
+type xorCompressor struct{}
+
+func (xorCompressor) Compress(dst, src []byte) ([]byte, error) {
+	for _, b := range src {
+		dst = append(dst, b^0xA5)
+	}
+	return dst, nil
+}
+
+func (xorCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	return xorCompressor{}.Compress(dst, src)
+}
+
+func init() {
+	if err := goavro.RegisterCompressor("test-xor", xorCompressor{}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	ensureError(t, goavro.RegisterCompressor("", xorCompressor{}), "cannot register compressor without name")
+	ensureError(t, goavro.RegisterCompressor("xor", nil), "cannot register nil compressor")
+	ensureError(t, goavro.RegisterCompressor(goavro.CompressionDeflateLabel, xorCompressor{}), "cannot register compressor using existing name")
+
+	ensureError(t, goavro.RegisterCompressor("test-xor", xorCompressor{}), "cannot register compressor using existing name")
+
+	testOCFRoundTrip(t, "test-xor")
+
+	// xorCompressor does not support compression levels
+	_, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:                new(bytes.Buffer),
+		CompressionName:  "test-xor",
+		CompressionLevel: 1,
+		Schema:           `{"type":"long"}`,
+	})
+	ensureError(t, err, "cannot use compression level with compression codec")
+}
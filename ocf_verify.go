@@ -0,0 +1,125 @@
+package goavro
+
+import (
+	"fmt"
+	"io"
+)
+
+// OCFReport describes the integrity of an OCF checked by VerifyOCF.
+type OCFReport struct {
+	// CompressionName is the name of the compression algorithm found within
+	// the OCF header.
+	CompressionName string
+
+	// Schema is the schema found within the OCF header.
+	Schema string
+
+	// Blocks describes each block whose framing could be read, in the order
+	// they appear in the OCF, including blocks whose data items could not be
+	// decompressed or decoded.
+	Blocks []OCFBlockReport
+
+	// Items is the number of data items successfully decoded.
+	Items int64
+
+	// Problems describes each corrupt region of the OCF, in the order they
+	// appear in the OCF. The OCF is valid when there are no problems.
+	Problems []OCFCorruption
+}
+
+// OCFBlockReport describes a block of an OCF checked by VerifyOCF.
+type OCFBlockReport struct {
+	// Offset is the offset of the block from the start of the OCF.
+	Offset int64
+
+	// Length is the number of bytes of the block, including its block count,
+	// block size, and sync marker.
+	Length int64
+
+	// Count is the number of data items declared by the block.
+	Count int64
+
+	// CompressedSize is the number of bytes of compressed data items.
+	CompressedSize int64
+
+	// UncompressedSize is the number of bytes of encoded data items, or 0 when
+	// the block could not be decompressed.
+	UncompressedSize int64
+
+	// Items is the number of data items successfully decoded.
+	Items int64
+}
+
+// VerifyOCF reads every block of the Avro Object Container File (OCF) from
+// ior, checking each sync marker, decompressing each block, which also checks
+// any checksum used by the compression algorithm, decoding each data item,
+// and ensuring no bytes remain after the final data item of each block.
+// Rather than stopping at the first problem, it resynchronizes on the next
+// sync marker in the same way as a reader created by NewOCFReaderWithConfig
+// with Recover, and reports every problem found. It only returns an error
+// when the OCF header cannot be read, or ior returns an error other than
+// io.EOF.
+//
+//     func example(ior io.Reader) error {
+//         report, err := goavro.VerifyOCF(bufio.NewReader(ior))
+//         if err != nil {
+//             return err
+//         }
+//         for _, problem := range report.Problems {
+//             fmt.Printf("offset %d: %s\n", problem.Offset, problem.Err)
+//         }
+//         if len(report.Problems) > 0 {
+//             return errors.New("corrupt OCF")
+//         }
+//         return nil
+//     }
+func VerifyOCF(ior io.Reader) (*OCFReport, error) {
+	rr := newResyncReader(ior)
+	header, err := readOCFHeader(rr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify OCF: %s", err)
+	}
+	ocfr := &OCFReader{header: header, ior: rr, rr: rr}
+	report := &OCFReport{CompressionName: header.compressionName, Schema: header.codec.Schema()}
+
+	for {
+		count, block, problems, err := ocfr.readNextBlock(report.Problems)
+		report.Problems = problems
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return report, fmt.Errorf("cannot verify OCF: %s", err)
+		}
+
+		stats := OCFBlockReport{
+			Offset:         ocfr.blockOffset,
+			Length:         ocfr.blockLength,
+			Count:          count,
+			CompressedSize: int64(len(block)),
+		}
+		problem := OCFCorruption{Offset: ocfr.blockOffset, Length: ocfr.blockLength}
+
+		buf, err := header.compressor.Decompress(nil, block)
+		if err != nil {
+			problem.Items, problem.Err = count, fmt.Errorf("cannot decompress: %s", err)
+		} else {
+			stats.UncompressedSize = int64(len(buf))
+			for ; stats.Items < count; stats.Items++ {
+				if _, buf, err = header.codec.NativeFromBinary(buf); err != nil {
+					problem.Items, problem.Err = count-stats.Items, err
+					break
+				}
+			}
+			if problem.Err == nil && len(buf) != 0 {
+				problem.Err = fmt.Errorf("extra bytes between final datum in previous block and block sync marker: %d", len(buf))
+			}
+		}
+
+		report.Blocks = append(report.Blocks, stats)
+		report.Items += stats.Items
+		if problem.Err != nil {
+			report.Problems = append(report.Problems, problem)
+		}
+	}
+}
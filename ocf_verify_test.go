@@ -0,0 +1,106 @@
+package goavro_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+func TestVerifyOCF(t *testing.T) {
+	for _, compressionName := range []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		buf := newOCFOfLongs(t, 100, 10, compressionName)
+		syncs := ocfSyncOffsets(buf)
+
+		report, err := goavro.VerifyOCF(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := report.CompressionName, compressionName; actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+		if actual, expected := report.Schema, `"long"`; actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+		if actual, expected := report.Items, int64(100); actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+		if actual, expected := len(report.Problems), 0; actual != expected {
+			t.Errorf("%s: Actual: %v; Expected: %v", compressionName, actual, expected)
+		}
+		if actual, expected := len(report.Blocks), 10; actual != expected {
+			t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+		}
+		for i, block := range report.Blocks {
+			if actual, expected := block.Offset, int64(syncs[i]+16); actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			if actual, expected := block.Length, int64(syncs[i+1]-syncs[i]); actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			if block.Count != 10 || block.Items != 10 || block.CompressedSize <= 0 || block.UncompressedSize <= 0 {
+				t.Errorf("Actual: %+v; Expected: 10 items", block)
+			}
+		}
+	}
+}
+
+func TestVerifyOCFProblems(t *testing.T) {
+	buf := newOCFOfLongs(t, 100, 10, goavro.CompressionNullLabel)
+	syncs := ocfSyncOffsets(buf)
+
+	buf[syncs[2]-1] |= 0x80 // block 1: final datum continues past end of block
+	buf[syncs[5]+7] ^= 0xff // blocks 4 and 5: sync marker mismatch
+	buf[syncs[8]-2] = 0x00  // block 7: final datum shortened, leaving extra byte
+
+	report, err := goavro.VerifyOCF(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := len(report.Blocks), 8; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := report.Items, int64(79); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := len(report.Problems), 3; actual != expected {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	ensureError(t, report.Problems[0].Err, "short buffer")
+	ensureError(t, report.Problems[1].Err, "sync marker mismatch")
+	ensureError(t, report.Problems[2].Err, "extra bytes")
+	for i, expected := range []goavro.OCFCorruption{
+		{Offset: int64(syncs[1] + 16), Length: int64(syncs[2] - syncs[1]), Items: 1},
+		{Offset: int64(syncs[4] + 16), Length: int64(syncs[6] - syncs[4]), Items: 10},
+		{Offset: int64(syncs[7] + 16), Length: int64(syncs[8] - syncs[7])},
+	} {
+		actual := report.Problems[i]
+		actual.Err = nil
+		if actual != expected {
+			t.Errorf("Actual: %+v; Expected: %+v", actual, expected)
+		}
+	}
+}
+
+func TestVerifyOCFFixtures(t *testing.T) {
+	for _, pathname := range []string{"fixtures/weather-null.avro", "fixtures/weather-deflate.avro", "fixtures/weather-zstandard.avro"} {
+		buf, err := ioutil.ReadFile(pathname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := goavro.VerifyOCF(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := len(report.Problems), 0; actual != expected {
+			t.Errorf("%s: Actual: %v; Expected: %v", pathname, actual, expected)
+		}
+		if actual, expected := report.Items, int64(len(readOCFData(t, buf))); actual != expected {
+			t.Errorf("%s: Actual: %v; Expected: %v", pathname, actual, expected)
+		}
+	}
+
+	_, err := goavro.VerifyOCF(bytes.NewReader([]byte("Obj")))
+	ensureError(t, err, "cannot verify OCF", "cannot read OCF header")
+}
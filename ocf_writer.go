@@ -2,54 +2,174 @@ package goavro
 
 import (
 	"bytes"
-	"compress/flate"
-	"encoding/binary"
 	"errors"
 	"fmt"
-	"hash/crc32"
 	"io"
 	"io/ioutil"
-	"os"
-
-	"github.com/golang/snappy"
+	"reflect"
 )
 
 // OCFConfig is used to specify creation parameters for OCFWriter.
 type OCFConfig struct {
 	// W specifies the `io.Writer` to which to send the encoded data,
-	// (required). If W is `*os.File`, then creating an OCF for writing will
-	// attempt to read any existing OCF header and use the schema and
-	// compression codec specified by the existing header, then advance the file
-	// position to the tail end of the file for appending.
+	// (required). If W is an `io.ReadWriteSeeker`, such as `*os.File`, which
+	// is not empty, then creating an OCF for writing will attempt to read any
+	// existing OCF header and use the schema and compression codec specified
+	// by the existing header, then advance the position to the tail end of
+	// the OCF for appending. A W which implements `io.Seeker`, but cannot
+	// seek, such as a pipe, is treated as any other `io.Writer`.
 	W io.Writer
 
+	// Append specifies that W must be appended to, (optional). If true, and
+	// W is not an `io.ReadWriteSeeker` able to seek, creating the OCFWriter
+	// returns an error rather than writing a new OCF header into what might
+	// be the middle of an existing OCF. When W is empty, a new OCF header is
+	// still written.
+	Append bool
+
+	// TruncateTornBlock specifies whether to truncate a torn block at the
+	// tail end of an existing OCF, (optional). When a process dies while
+	// writing a block, the OCF ends with an incomplete block, to which no
+	// more blocks may be appended. If true, such an incomplete block is
+	// truncated, along with any other bytes following the final complete
+	// block, and appending continues from there. The number of bytes
+	// discarded is reported by the TruncatedBytes method. W must also
+	// implement a `Truncate(size int64) error` method, such as `*os.File`.
+	// Blocks which are complete but corrupt are never truncated.
+	TruncateTornBlock bool
+
 	// Codec specifies the Codec to use for the new OCFWriter, (optional). If
-	// the W parameter above is an `*os.File` which contains a Codec, the Codec
-	// in the existing file will be used instead. Otherwise if this Codec
-	// parameter is specified, it will be used. If neither the W parameter above
-	// is an `*os.File` with an existing Codec, nor this Codec parameter is
-	// specified, the OCFWriter will create a new Codec from the schema string
-	// specified by the Schema parameter below.
+	// the W parameter above is an `io.ReadWriteSeeker` which contains a Codec,
+	// the Codec in the existing OCF will be used instead. Otherwise if this
+	// Codec parameter is specified, it will be used. If neither the W
+	// parameter above is an `io.ReadWriteSeeker` with an existing Codec, nor
+	// this Codec parameter is specified, the OCFWriter will create a new Codec
+	// from the schema string specified by the Schema parameter below.
 	Codec *Codec
 
 	// Schema specifies the Avro schema for the data to be encoded, (optional).
-	// If neither the W parameter above is an `*os.File` with an existing Codec,
-	// nor the Codec parameter above is specified, the OCFWriter will create a
-	// new Codec from the schema string specified by this Schema parameter.
+	// If neither the W parameter above is an `io.ReadWriteSeeker` with an
+	// existing Codec, nor the Codec parameter above is specified, the
+	// OCFWriter will create a new Codec from the schema string specified by
+	// this Schema parameter.
 	Schema string
 
 	// CompressionName specifies the compression codec used, (optional). If
-	// omitted, defaults to "null" codec. When appending to an existing OCF,
-	// this field is ignored.
+	// omitted, defaults to "null" codec. It may be the name of a built-in
+	// codec, or of a Compressor registered using RegisterCompressor. When
+	// appending to an existing OCF, this field is ignored.
 	CompressionName string
+
+	// CompressionLevel specifies the compression level used by the compression
+	// codec, (optional). If omitted, or 0, the default level of the codec is
+	// used. The "deflate" codec accepts the levels defined by the
+	// compress/flate package, from flate.HuffmanOnly through
+	// flate.BestCompression, other than flate.NoCompression, which cannot be
+	// distinguished from omitting the level. The "zstandard" codec accepts
+	// levels 1 through 22, the "bzip2" codec accepts levels 1 through 9, and
+	// the "xz" codec accepts presets 1 through 9, mirroring the levels of their
+	// respective command line tools. However, an "xz" preset only selects the
+	// dictionary size of the corresponding xz preset, which rarely affects
+	// blocks smaller than the dictionary, so it is not a substitute for the
+	// compression ratio of xz at that preset. A registered Compressor supports
+	// compression levels by implementing LevelCompressor. When appending to an
+	// existing OCF, this field is still honored for the compression codec
+	// specified by the existing header.
+	CompressionLevel int
+
+	// MetaData specifies application specific metadata to be stored in the
+	// OCF header, (optional). Keys starting with "avro." are reserved by the
+	// Avro specification, and may not be used. When appending to an existing
+	// OCF, this field is ignored, and the metadata from the existing OCF header
+	// is preserved.
+	MetaData map[string][]byte
+
+	// SyncMarker specifies the 16 byte sync marker of a new OCF, (optional).
+	// If omitted, and SyncMarkerSeed is also omitted, a random sync marker is
+	// generated. Because metadata is always written in a stable order, an OCF
+	// written with the same configuration and data items is always written
+	// as the same bytes when its sync marker is specified. When appending to
+	// an existing OCF, this field is ignored.
+	SyncMarker []byte
+
+	// SyncMarkerSeed specifies a seed from which the sync marker of a new OCF
+	// is derived, (optional). If not empty, the sync marker is derived from a
+	// SHA-256 hash of this seed and the schema, rather than being specified
+	// by SyncMarker. When appending to an existing OCF, this field is
+	// ignored.
+	SyncMarkerSeed string
+
+	// BlockCount specifies the number of data items Write buffers before
+	// emitting them as a block, (optional). If omitted, or 0, Write emits a
+	// block only when BlockSize is reached, or when the block would
+	// otherwise contain more than MaxBlockCount items. It has no effect on
+	// Append.
+	BlockCount int
+
+	// BlockSize specifies the number of encoded bytes Write buffers before
+	// emitting them as a block, (optional). If omitted, or 0, defaults to
+	// DefaultBlockSize. It has no effect on Append.
+	BlockSize int
+
+	// Concurrency specifies the number of go routines used to encode and
+	// compress blocks, (optional). If omitted, 0, or 1, blocks are encoded,
+	// compressed, and written by the go routine calling Write, Append, Flush
+	// or Close. Otherwise, blocks are encoded and compressed by a pool of
+	// worker go routines, and written to W in their original order by another
+	// go routine. In that case, data items provided to Write, Append, and
+	// AppendStructs must not be modified until Flush or Close returns, and
+	// when BlockCount is 0, blocks have approximately BlockSize bytes, based
+	// on the encoded size of previous data items. Also, an error encoding a
+	// data item is returned by the following call to Flush or Close, rather
+	// than the call that provided it, which returns nil. As when Concurrency
+	// is 1, such a data item provided to Write is not written, but the other
+	// data items are, while no data items of its block are written when it
+	// was provided to Append or AppendStructs. The OCFWriter must be released
+	// by calling Close.
+	Concurrency int
+
+	// MaxPendingBlocks specifies the maximum number of blocks that may be
+	// waiting to be encoded, compressed, or written when Concurrency is greater
+	// than 1, (optional). If omitted, or 0, defaults to twice Concurrency. Once
+	// this many blocks are pending, calls to Write and Append which emit
+	// another block wait for a pending block to be written.
+	MaxPendingBlocks int
 }
 
+// DefaultBlockSize is the default number of encoded bytes an OCFWriter buffers
+// from calls to Write before emitting them as a block. It matches the default
+// sync interval of the Avro reference implementation.
+const DefaultBlockSize = 64000
+
 // OCFWriter is used to create a new or append to an existing Avro Object
 // Container File (OCF).
 type OCFWriter struct {
-	header *ocfHeader
-	err    error
-	iow    io.Writer
+	header     *ocfHeader
+	compressor Compressor // header compressor, configured with compression level
+	err        error      // most recent error that took place while writing bytes (unrecoverable)
+	iow        io.Writer
+	closed     bool               // true after Close
+	pipeline   *ocfWriterPipeline // only used when Concurrency greater than 1
+	truncated  int64              // count of bytes of torn block truncated from existing OCF
+
+	// Thresholds at which data items buffered by Write are emitted as a block.
+	blockCount int
+	blockSize  int
+
+	// Working buffers reused for each block, to avoid allocating new buffers
+	// for every block appended to the OCF.
+	block      []byte // encoded data values
+	blockItems int    // count of data items encoded into block by Write, but not yet emitted
+	compressed []byte // compressed block
+	buf        []byte // block count, block size, compressed block, and sync marker
+
+	// Only used when Concurrency greater than 1, where Write buffers data items
+	// rather than encoding them, so they may be encoded by a worker.
+	data      []interface{} // data items buffered by Write, but not yet emitted
+	dataLimit int           // count of data items Write buffers before emitting them
+
+	// Only used by AppendStructs, which binds the schema to each Go type once.
+	encoders map[reflect.Type]bindingEncoder
 }
 
 // NewOCFWriter returns a new OCFWriter instance that may be used for appending
@@ -57,39 +177,75 @@ type OCFWriter struct {
 // new OCF file.
 func NewOCFWriter(config OCFConfig) (*OCFWriter, error) {
 	var err error
-	ocf := &OCFWriter{iow: config.W}
+	if config.BlockCount < 0 {
+		return nil, fmt.Errorf("cannot create OCFWriter when BlockCount is negative: %d", config.BlockCount)
+	}
+	if config.BlockSize < 0 {
+		return nil, fmt.Errorf("cannot create OCFWriter when BlockSize is negative: %d", config.BlockSize)
+	}
+	if config.Concurrency < 0 {
+		return nil, fmt.Errorf("cannot create OCFWriter when Concurrency is negative: %d", config.Concurrency)
+	}
+	if config.MaxPendingBlocks < 0 {
+		return nil, fmt.Errorf("cannot create OCFWriter when MaxPendingBlocks is negative: %d", config.MaxPendingBlocks)
+	}
+	ocf := &OCFWriter{iow: config.W, blockCount: config.BlockCount, blockSize: config.BlockSize}
+	if ocf.blockSize == 0 {
+		ocf.blockSize = DefaultBlockSize
+	}
 
-	switch config.W.(type) {
-	case nil:
+	if config.W == nil {
 		return nil, errors.New("cannot create OCFWriter when W is nil")
-	case *os.File:
-		file := config.W.(*os.File)
-		stat, err := file.Stat()
-		if err != nil {
-			return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
-		}
+	}
+	if rws, ok := config.W.(io.ReadWriteSeeker); ok {
 		// NOTE: When upstream provides a new file, it will already exist but
-		// have a size of 0 bytes.
-		if stat.Size() > 0 {
+		// have a size of 0 bytes. Some writers, such as pipes, implement
+		// io.Seeker but cannot seek.
+		size, err := rws.Seek(0, io.SeekEnd)
+		if err != nil {
+			if config.Append {
+				return nil, fmt.Errorf("cannot create OCFWriter in Append mode: %s", err)
+			}
+		} else if size > 0 {
+			if _, err = rws.Seek(0, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+			}
 			// attempt to read existing OCF header
-			if ocf.header, err = readOCFHeader(file); err != nil {
+			if ocf.header, err = readOCFHeader(rws); err != nil {
 				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 			}
-			// prepare for appending data to existing OCF
-			if err = ocf.quickScanToTail(file); err != nil {
+			if ocf.compressor, err = compressorWithLevel(ocf.header.compressionName, ocf.header.compressor, config.CompressionLevel); err != nil {
 				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 			}
+			// prepare for appending data to existing OCF
+			tail, torn, err := ocf.quickScanToTail(rws)
+			if err != nil {
+				if !torn || !config.TruncateTornBlock {
+					return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+				}
+				if err = truncateTornBlock(rws, tail); err != nil {
+					return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+				}
+				ocf.truncated = size - tail
+			}
+			ocf.startPipeline(config)
 			return ocf, nil // happy case for appending to existing OCF
 		}
+	} else if config.Append {
+		return nil, fmt.Errorf("cannot create OCFWriter in Append mode when W is not an io.ReadWriteSeeker: %T", config.W)
 	}
 
 	// create new OCF header based on configuration parameters
 	if ocf.header, err = newOCFHeader(config); err != nil {
 		return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 	}
+	if ocf.compressor, err = compressorWithLevel(ocf.header.compressionName, ocf.header.compressor, config.CompressionLevel); err != nil {
+		return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+	}
 	if err = writeOCFHeader(ocf.header, config.W); err != nil {
 		return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 	}
+	ocf.startPipeline(config)
 	return ocf, nil // another happy case for creation of new OCF
 }
 
@@ -97,59 +253,119 @@ func NewOCFWriter(config OCFConfig) (*OCFWriter, error) {
 // file. Rather than reading each encoded block, optionally decompressing it,
 // and then decoding it, this method reads the block count, ignoring it, then
 // reads the block size, then skips ahead to the followig block. It does this
-// repeatedly until attempts to read the file return io.EOF.
-func (ocfw *OCFWriter) quickScanToTail(ior io.Reader) error {
+// repeatedly until attempts to read the file return io.EOF. It returns the
+// offset of the end of the final complete block, and when it returns an error,
+// whether the error is due to the file ending before the end of a block.
+func (ocfw *OCFWriter) quickScanToTail(rs io.ReadSeeker) (int64, bool, error) {
+	tail, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false, err
+	}
+	torn := func(err error) bool { return err == io.EOF || err == io.ErrUnexpectedEOF }
+
 	sync := make([]byte, ocfSyncLength)
 	for {
 		// Read and validate block count
-		blockCount, err := longBinaryReader(ior)
+		blockCount, err := longBinaryReader(rs)
 		if err != nil {
 			if err == io.EOF {
-				return nil // merely end of file, rather than error
+				// NOTE: The file may end part way through the block count.
+				offset, err := rs.Seek(0, io.SeekCurrent)
+				if err != nil {
+					return tail, false, err
+				}
+				if offset == tail {
+					return tail, false, nil // merely end of file, rather than error
+				}
+				return tail, true, fmt.Errorf("cannot read block count: %s", io.ErrUnexpectedEOF)
 			}
-			return fmt.Errorf("cannot read block count: %s", err)
+			return tail, false, fmt.Errorf("cannot read block count: %s", err)
 		}
 		if blockCount <= 0 {
-			return fmt.Errorf("cannot read when block count is not greater than 0: %d", blockCount)
+			return tail, false, fmt.Errorf("cannot read when block count is not greater than 0: %d", blockCount)
 		}
 		if blockCount > MaxBlockCount {
-			return fmt.Errorf("cannot read when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
+			return tail, false, fmt.Errorf("cannot read when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
 		}
 		// Read block size
-		blockSize, err := longBinaryReader(ior)
+		blockSize, err := longBinaryReader(rs)
 		if err != nil {
-			return fmt.Errorf("cannot read block size: %s", err)
+			return tail, torn(err), fmt.Errorf("cannot read block size: %s", err)
 		}
 		if blockSize <= 0 {
-			return fmt.Errorf("cannot read when block size is not greater than 0: %d", blockSize)
+			return tail, false, fmt.Errorf("cannot read when block size is not greater than 0: %d", blockSize)
 		}
 		if blockSize > MaxBlockSize {
-			return fmt.Errorf("cannot read when block size exceeds MaxBlockSize: %d > %d", blockSize, MaxBlockSize)
+			return tail, false, fmt.Errorf("cannot read when block size exceeds MaxBlockSize: %d > %d", blockSize, MaxBlockSize)
 		}
 		// Advance reader to end of block
-		if _, err = io.CopyN(ioutil.Discard, ior, blockSize); err != nil {
-			return fmt.Errorf("cannot seek to next block: %s", err)
+		if _, err = io.CopyN(ioutil.Discard, rs, blockSize); err != nil {
+			return tail, torn(err), fmt.Errorf("cannot seek to next block: %s", err)
 		}
 		// Read and validate sync marker
 		var n int
-		if n, err = io.ReadFull(ior, sync); err != nil {
-			return fmt.Errorf("cannot read sync marker: read %d out of %d bytes: %s", n, ocfSyncLength, err)
+		if n, err = io.ReadFull(rs, sync); err != nil {
+			return tail, torn(err), fmt.Errorf("cannot read sync marker: read %d out of %d bytes: %s", n, ocfSyncLength, err)
 		}
 		if !bytes.Equal(sync, ocfw.header.syncMarker[:]) {
-			return fmt.Errorf("sync marker mismatch: %v != %v", sync, ocfw.header.syncMarker)
+			return tail, false, fmt.Errorf("sync marker mismatch: %v != %v", sync, ocfw.header.syncMarker)
+		}
+		if tail, err = rs.Seek(0, io.SeekCurrent); err != nil {
+			return tail, false, err
 		}
 	}
 }
 
+// truncateTornBlock truncates the file to size bytes, discarding a torn block
+// at its tail end, and positions it at its new tail end for appending.
+func truncateTornBlock(rws io.ReadWriteSeeker, size int64) error {
+	truncater, ok := rws.(interface{ Truncate(int64) error })
+	if !ok {
+		return fmt.Errorf("cannot truncate torn block when W does not implement Truncate: %T", rws)
+	}
+	if err := truncater.Truncate(size); err != nil {
+		return fmt.Errorf("cannot truncate torn block: %s", err)
+	}
+	if _, err := rws.Seek(size, io.SeekStart); err != nil {
+		return fmt.Errorf("cannot truncate torn block: %s", err)
+	}
+	return nil
+}
+
+// TruncatedBytes returns the number of bytes of a torn block truncated from
+// the tail end of an existing OCF when the OCFWriter was created with
+// TruncateTornBlock, or 0 when no bytes were truncated.
+func (ocfw *OCFWriter) TruncatedBytes() int64 {
+	return ocfw.truncated
+}
+
 // Append appends one or more data items to an OCF file in a block. If there are
 // more data items in the slice than MaxBlockCount allows, the data slice will
 // be chunked into multiple blocks, each not having more than MaxBlockCount
-// items.
+// items. Any data items buffered by Write are emitted in their own block
+// before the data items provided to Append.
 func (ocfw *OCFWriter) Append(data interface{}) error {
 	arrayValues, err := convertArray(data)
 	if err != nil {
 		return err
 	}
+	if ocfw.closed {
+		return errors.New("cannot append to closed OCFWriter")
+	}
+	if ocfw.pipeline != nil {
+		if err = ocfw.emitBufferedBlock(); err != nil {
+			return err
+		}
+		if _, ok := data.([]interface{}); ok {
+			// NOTE: Data items are encoded after Append returns, so copy the
+			// slice, which the caller may reuse.
+			arrayValues = append([]interface{}(nil), arrayValues...)
+		}
+		return ocfw.submitData(len(arrayValues), false, ocfw.nativeEncoder(arrayValues))
+	}
+	if err = ocfw.Flush(); err != nil {
+		return err
+	}
 
 	// Chunk data so no block has more than MaxBlockCount items.
 	for int64(len(arrayValues)) > MaxBlockCount {
@@ -161,8 +377,100 @@ func (ocfw *OCFWriter) Append(data interface{}) error {
 	return ocfw.appendDataIntoBlock(arrayValues)
 }
 
+// Write encodes a single data item and buffers it, emitting the buffered data
+// items as a block once BlockSize bytes or BlockCount items have been
+// buffered, or when the block would otherwise contain more than MaxBlockCount
+// items. Because writing the block to the underlying io.Writer is deferred,
+// this method may return an error resulting from writing previously buffered
+// data items. Once an error compressing a block or writing to the underlying
+// io.Writer has occurred, it is returned by all subsequent calls. Call Flush or
+// Close to emit any remaining buffered data items.
+func (ocfw *OCFWriter) Write(datum interface{}) error {
+	if ocfw.closed {
+		return errors.New("cannot write to closed OCFWriter")
+	}
+	if ocfw.err != nil {
+		return ocfw.err
+	}
+	if ocfw.pipeline != nil {
+		return ocfw.writePipeline(datum)
+	}
+	if ocfw.blockItems == 0 {
+		ocfw.block = ocfw.block[:0]
+	}
+	block, err := ocfw.header.codec.BinaryFromNative(ocfw.block, datum)
+	if err != nil {
+		// NOTE: ocfw.block still has its original length, so the partially
+		// encoded datum is discarded.
+		return fmt.Errorf("cannot translate datum to binary: %v; %s", datum, err)
+	}
+	ocfw.block = block
+	ocfw.blockItems++
+
+	if len(ocfw.block) >= ocfw.blockSize || int64(ocfw.blockItems) >= MaxBlockCount || (ocfw.blockCount > 0 && ocfw.blockItems >= ocfw.blockCount) {
+		return ocfw.emitBufferedBlock()
+	}
+	return nil
+}
+
+// Flush emits any data items buffered by Write as a block. When Concurrency is
+// greater than 1, it also waits for all pending blocks to be written, and
+// returns the first error that occurred while compressing or writing them, or
+// otherwise, the first error encoding a data item provided since the previous
+// call to Flush. It
+// does not flush the underlying io.Writer.
+func (ocfw *OCFWriter) Flush() error {
+	if ocfw.closed {
+		return errors.New("cannot flush closed OCFWriter")
+	}
+	if err := ocfw.emitBufferedBlock(); err != nil {
+		return err
+	}
+	if ocfw.pipeline != nil {
+		return ocfw.waitPipeline()
+	}
+	return nil
+}
+
+// emitBufferedBlock emits any data items buffered by Write as a block.
+func (ocfw *OCFWriter) emitBufferedBlock() error {
+	if ocfw.err != nil {
+		return ocfw.err
+	}
+	if ocfw.pipeline != nil {
+		if len(ocfw.data) == 0 {
+			return nil
+		}
+		data := ocfw.data
+		ocfw.data = make([]interface{}, 0, len(data))
+		return ocfw.submitData(len(data), true, ocfw.nativeEncoder(data))
+	}
+	if ocfw.blockItems == 0 {
+		return nil
+	}
+	count := ocfw.blockItems
+	ocfw.blockItems = 0
+	return ocfw.writeBlock(count)
+}
+
+// Close emits any data items buffered by Write as a block, and returns any
+// error that occurred while writing to the underlying io.Writer. It does not
+// close the underlying io.Writer. After Close, calls to Write, Flush and
+// Append return an error, and subsequent calls to Close return nil.
+func (ocfw *OCFWriter) Close() error {
+	if ocfw.closed {
+		return nil
+	}
+	err := ocfw.Flush()
+	if ocfw.pipeline != nil {
+		ocfw.stopPipeline()
+	}
+	ocfw.closed = true
+	return err
+}
+
 func (ocfw *OCFWriter) appendDataIntoBlock(data []interface{}) error {
-	var block []byte // working buffer for encoding data values
+	block := ocfw.block[:0] // working buffer for encoding data values
 	var err error
 
 	// Encode and concatenate each data item into the block
@@ -171,48 +479,92 @@ func (ocfw *OCFWriter) appendDataIntoBlock(data []interface{}) error {
 			return fmt.Errorf("cannot translate datum to binary: %v; %s", datum, err)
 		}
 	}
+	ocfw.block = block
 
-	switch ocfw.header.compressionID {
-	case compressionNull:
-		// no-op
+	return ocfw.writeBlock(len(data))
+}
 
-	case compressionDeflate:
-		// compress into new bytes buffer.
-		bb := bytes.NewBuffer(make([]byte, 0, len(block)))
+// writeBlock compresses the data items encoded in the block buffer, and writes
+// them to the underlying io.Writer as a single block having count items.
+func (ocfw *OCFWriter) writeBlock(count int) error {
+	if ocfw.pipeline != nil {
+		return ocfw.submitBlock(count)
+	}
 
-		cw, _ := flate.NewWriter(bb, flate.DefaultCompression)
-		// writing bytes to cw will compress bytes and send to bb.
-		if _, err := cw.Write(block); err != nil {
-			return err
-		}
-		if err := cw.Close(); err != nil {
-			return err
-		}
-		block = bb.Bytes()
+	block, err := ocfw.compressor.Compress(ocfw.compressed[:0], ocfw.block)
+	if err != nil {
+		// NOTE: The data items of the block have been discarded, so, just as
+		// when writing the block fails, no more data items may be written.
+		ocfw.err = fmt.Errorf("cannot compress block: %s", err)
+		return ocfw.err
+	}
+	ocfw.compressed = block
 
-	case compressionSnappy:
-		compressed := snappy.Encode(nil, block)
+	return ocfw.writeCompressedBlock(count, block)
+}
 
-		// OCF requires snappy to have CRC32 checksum after each snappy block
-		compressed = append(compressed, 0, 0, 0, 0)                                           // expand slice by 4 bytes so checksum will fit
-		binary.BigEndian.PutUint32(compressed[len(compressed)-4:], crc32.ChecksumIEEE(block)) // checksum of decompressed block
+// writeCompressedBlock writes the already compressed block having count items
+// to the underlying io.Writer.
+func (ocfw *OCFWriter) writeCompressedBlock(count int, block []byte) error {
+	if ocfw.pipeline != nil {
+		return ocfw.submitCompressedBlock(count, block)
+	}
+
+	ocfw.buf = appendOCFBlock(ocfw.buf[:0], count, block, &ocfw.header.syncMarker)
 
-		block = compressed
+	_, err := ocfw.iow.Write(ocfw.buf)
+	if err != nil {
+		ocfw.err = err
+	}
+	return err
+}
 
-	default:
-		return fmt.Errorf("should not get here: cannot compress block using unrecognized compression: %d", ocfw.header.compressionID)
+// appendOCFBlock appends the block count, block size, compressed block, and
+// sync marker of a file data block to buf, and returns the resulting slice.
+func appendOCFBlock(buf []byte, count int, block []byte, syncMarker *[ocfSyncLength]byte) []byte {
+	buf = growBytes(buf, len(block)+ocfBlockConst) // pre-allocate block bytes
+	buf, _ = longBinaryFromNative(buf, count)      // block count (number of data items)
+	buf, _ = longBinaryFromNative(buf, len(block)) // block size (number of bytes in block)
+	buf = append(buf, block...)                    // serialized objects
+	buf = append(buf, syncMarker[:]...)            // sync marker
+	return buf
+}
 
+// AppendRawBlock appends a block read by OCFReader.NextBlock to the OCF,
+// without decoding its data items, after emitting any data items buffered by
+// Write. When the block is compressed using the same compression algorithm as
+// this OCF, its bytes are copied verbatim, otherwise they are decompressed and
+// then compressed using the compression algorithm of this OCF. The caller is
+// responsible for ensuring the block was read from an OCF having the same
+// schema as this OCF.
+func (ocfw *OCFWriter) AppendRawBlock(block OCFBlock) error {
+	if ocfw.closed {
+		return errors.New("cannot append raw block to closed OCFWriter")
+	}
+	if block.Count <= 0 {
+		return fmt.Errorf("cannot append raw block when block count is not greater than 0: %d", block.Count)
+	}
+	if block.Count > MaxBlockCount {
+		return fmt.Errorf("cannot append raw block when block count exceeds MaxBlockCount: %d > %d", block.Count, MaxBlockCount)
+	}
+	if err := ocfw.emitBufferedBlock(); err != nil {
+		return err
 	}
 
-	// create file data block
-	buf := make([]byte, 0, len(block)+ocfBlockConst) // pre-allocate block bytes
-	buf, _ = longBinaryFromNative(buf, len(data))    // block count (number of data items)
-	buf, _ = longBinaryFromNative(buf, len(block))   // block size (number of bytes in block)
-	buf = append(buf, block...)                      // serialized objects
-	buf = append(buf, ocfw.header.syncMarker[:]...)  // sync marker
+	if block.CompressionName == ocfw.header.compressionName {
+		return ocfw.writeCompressedBlock(int(block.Count), block.Data)
+	}
 
-	_, err = ocfw.iow.Write(buf)
-	return err
+	compressor, ok := compressorFromName(block.CompressionName)
+	if !ok {
+		return fmt.Errorf("cannot append raw block using unrecognized compression algorithm: %q", block.CompressionName)
+	}
+	decompressed, err := compressor.Decompress(ocfw.block[:0], block.Data)
+	if err != nil {
+		return fmt.Errorf("cannot append raw block: cannot decompress: %s", err)
+	}
+	ocfw.block = decompressed
+	return ocfw.writeBlock(int(block.Count))
 }
 
 // Codec returns the codec used by OCFWriter. This function provided because
@@ -222,19 +574,19 @@ func (ocfw *OCFWriter) Codec() *Codec {
 	return ocfw.header.codec
 }
 
+// MetaData returns the application specific metadata stored in the OCF header,
+// excluding the keys reserved by the Avro specification. This function provided
+// because upstream may be appending to existing OCF which has different
+// metadata than requested during instantiation. The returned map ought not be
+// modified.
+func (ocfw *OCFWriter) MetaData() map[string][]byte {
+	return ocfw.header.metadata
+}
+
 // CompressionName returns the name of the compression algorithm used by
 // OCFWriter. This function provided because upstream may be appending to
 // existing OCF which uses a different compression algorithm than requested
 // during instantiation.  the OCF file.
 func (ocfw *OCFWriter) CompressionName() string {
-	switch ocfw.header.compressionID {
-	case compressionNull:
-		return CompressionNullLabel
-	case compressionDeflate:
-		return CompressionDeflateLabel
-	case compressionSnappy:
-		return CompressionSnappyLabel
-	default:
-		return "should not get here: unrecognized compression algorithm"
-	}
+	return ocfw.header.compressionName
 }
@@ -0,0 +1,113 @@
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// AppendStructs appends the items of data, which must be a slice, to an OCF
+// file in a block, encoding each item directly into the block, without first
+// converting it to native Go data. Like Append, if there are more items in
+// the slice than MaxBlockCount allows, they are chunked into multiple blocks,
+// and any data items buffered by Write are emitted in their own block before
+// the items provided to AppendStructs.
+//
+// The schema of the OCF is bound to the slice item type the first time
+// AppendStructs is called with a slice having items of that type, and the
+// binding is reused for every subsequent item, so encoding items does not
+// allocate. Avro records are typically encoded from Go structs, or pointers
+// to structs, bound as described by the ReadInto method of OCFReader. Record
+// fields without a corresponding struct field are encoded using their default
+// value, and it is an error when a record field has neither a corresponding
+// struct field nor a default value. Any other Go type to which the schema may
+// be bound is also accepted, for instance []int64 for a schema of long. Unions
+// with null are encoded from pointers, using the first non-null union member
+// to which the pointer element type may be bound, and null when the pointer is
+// nil.
+//
+//     type Person struct {
+//         Name  string  `avro:"name"`
+//         Age   int     `avro:"age"`
+//         Email *string `avro:"email"` // ["null","string"]
+//     }
+//
+//     func example(ocfw *goavro.OCFWriter, people []Person) error {
+//         return ocfw.AppendStructs(people)
+//     }
+func (ocfw *OCFWriter) AppendStructs(data interface{}) error {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot append structs: expected slice; received: %T", data)
+	}
+	encoder, err := ocfw.binding(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	if ocfw.closed {
+		return errors.New("cannot append structs to closed OCFWriter")
+	}
+	if ocfw.pipeline != nil {
+		if err = ocfw.emitBufferedBlock(); err != nil {
+			return err
+		}
+		// NOTE: Data items are encoded after AppendStructs returns, so copy
+		// the slice, which the caller may reuse.
+		items := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(items, v)
+		return ocfw.submitData(items.Len(), false, func(buf []byte, i int) ([]byte, error) {
+			buf, err := encoder(buf, items.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("cannot translate datum to binary: %v; %s", items.Index(i), err)
+			}
+			return buf, nil
+		})
+	}
+	if err = ocfw.Flush(); err != nil {
+		return err
+	}
+
+	// Chunk data so no block has more than MaxBlockCount items. Like Append,
+	// an empty slice is written as an empty block.
+	for int64(v.Len()) > MaxBlockCount {
+		if err = ocfw.appendValuesIntoBlock(encoder, v.Slice(0, int(MaxBlockCount))); err != nil {
+			return err
+		}
+		v = v.Slice(int(MaxBlockCount), v.Len())
+	}
+	return ocfw.appendValuesIntoBlock(encoder, v)
+}
+
+// appendValuesIntoBlock encodes each item of the slice v into the block using
+// encoder, and writes the block.
+func (ocfw *OCFWriter) appendValuesIntoBlock(encoder bindingEncoder, v reflect.Value) error {
+	block := ocfw.block[:0] // working buffer for encoding data values
+	var err error
+
+	// Encode and concatenate each data item into the block
+	for i := 0; i < v.Len(); i++ {
+		if block, err = encoder(block, v.Index(i)); err != nil {
+			return fmt.Errorf("cannot translate datum to binary: %v; %s", v.Index(i), err)
+		}
+	}
+	ocfw.block = block
+
+	return ocfw.writeBlock(v.Len())
+}
+
+// binding returns the encoder binding the schema of the OCF to t, binding it
+// the first time it is needed.
+func (ocfw *OCFWriter) binding(t reflect.Type) (bindingEncoder, error) {
+	if encoder, ok := ocfw.encoders[t]; ok {
+		return encoder, nil
+	}
+	encoder, err := bindEncoder(ocfw.header.codec, t)
+	if err != nil {
+		return nil, fmt.Errorf("cannot append Go %s: %s", t, err)
+	}
+	if ocfw.encoders == nil {
+		ocfw.encoders = make(map[reflect.Type]bindingEncoder)
+	}
+	ocfw.encoders[t] = encoder
+	return encoder, nil
+}
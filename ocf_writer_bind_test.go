@@ -0,0 +1,290 @@
+package goavro_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+const bindEventSchema = `{"type":"record","name":"Event","fields":[
+	{"name":"id","type":"long"},
+	{"name":"name","type":"string"},
+	{"name":"score","type":"double"},
+	{"name":"ratio","type":"float"},
+	{"name":"count","type":"int"},
+	{"name":"flags","type":{"type":"array","items":"boolean"}},
+	{"name":"labels","type":{"type":"map","values":"string"}},
+	{"name":"kind","type":{"type":"enum","name":"Kind","symbols":["CREATE","UPDATE","DELETE"]}},
+	{"name":"hash","type":{"type":"fixed","name":"Hash","size":2}},
+	{"name":"parent","type":["null","Event"]},
+	{"name":"note","type":["null","string"]},
+	{"name":"payload","type":"bytes"},
+	{"name":"version","type":"int","default":1},
+	{"name":"extra","type":["null","long"],"default":null}
+]}`
+
+type bindEvent struct {
+	ID      int64
+	Name    string
+	Score   float64
+	Ratio   float32
+	Count   uint16
+	Flags   []bool
+	Labels  map[string]string
+	Kind    string
+	Hash    [2]byte
+	Parent  *bindEvent
+	Note    *string
+	Payload []byte
+}
+
+func bindEventNative(id int64, parent, note interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"name":    "event",
+		"score":   0.1,
+		"ratio":   float32(0.5),
+		"count":   int32(7),
+		"flags":   []interface{}{true, false},
+		"labels":  map[string]interface{}{"env": "test"},
+		"kind":    "UPDATE",
+		"hash":    []byte{0xab, 0xcd},
+		"parent":  parent,
+		"note":    note,
+		"payload": []byte("payload"),
+		"version": int32(1),
+		"extra":   nil,
+	}
+}
+
+func newBindEvent(id int64, parent *bindEvent, note *string) bindEvent {
+	return bindEvent{
+		ID:      id,
+		Name:    "event",
+		Score:   0.1,
+		Ratio:   0.5,
+		Count:   7,
+		Flags:   []bool{true, false},
+		Labels:  map[string]string{"env": "test"},
+		Kind:    "UPDATE",
+		Hash:    [2]byte{0xab, 0xcd},
+		Parent:  parent,
+		Note:    note,
+		Payload: []byte("payload"),
+	}
+}
+
+func TestOCFWriterAppendStructs(t *testing.T) {
+	note := "noted"
+	first := newBindEvent(1, nil, nil)
+	events := []bindEvent{first, newBindEvent(2, &first, &note)}
+	natives := []interface{}{
+		bindEventNative(1, nil, nil),
+		bindEventNative(2, goavro.Union("Event", bindEventNative(1, nil, nil)), goavro.Union("string", note)),
+	}
+
+	for _, concurrency := range []int{0, 2} {
+		config := goavro.OCFConfig{Schema: bindEventSchema, SyncMarkerSeed: "bind", Concurrency: concurrency}
+
+		bb := new(bytes.Buffer)
+		config.W = bb
+		ocfw, err := goavro.NewOCFWriter(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, native := range natives {
+			if err = ocfw.Append([]interface{}{native}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = ocfw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		expected := append([]byte(nil), bb.Bytes()...)
+
+		bb.Reset()
+		if ocfw, err = goavro.NewOCFWriter(config); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.AppendStructs(events[:1]); err != nil {
+			t.Fatal(err)
+		}
+		// pointers to structs bind separately, and are encoded identically
+		if err = ocfw.AppendStructs([]*bindEvent{&events[1]}); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if actual := bb.Bytes(); !bytes.Equal(actual, expected) {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+
+		// structs read back are those appended
+		ocfr, err := goavro.NewOCFReader(bytes.NewReader(bb.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []bindEvent
+		for ocfr.Scan() {
+			var event bindEvent
+			if err = ocfr.ReadInto(&event); err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, event)
+		}
+		if err = ocfr.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, events) {
+			t.Errorf("Concurrency: %d; Actual: %+v; Expected: %+v", concurrency, actual, events)
+		}
+	}
+}
+
+func TestOCFWriterAppendStructsTypedSlice(t *testing.T) {
+	mf := new(memFile)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: mf, Schema: `"long"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.AppendStructs([]int64{0, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.AppendStructs([]int{3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := readOCFData(t, mf.buf), []interface{}{int64(0), int64(1), int64(2), int64(3), int64(4)}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := countOCFBlocks(mf.buf), 2; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestOCFWriterAppendStructsEmpty(t *testing.T) {
+	// like Append, an empty slice is written as an empty block
+	for _, concurrency := range []int{0, 2} {
+		config := goavro.OCFConfig{Schema: bindEventSchema, SyncMarkerSeed: "bind", Concurrency: concurrency}
+
+		bb := new(bytes.Buffer)
+		config.W = bb
+		ocfw, err := goavro.NewOCFWriter(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Append([]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		expected := append([]byte(nil), bb.Bytes()...)
+
+		bb.Reset()
+		if ocfw, err = goavro.NewOCFWriter(config); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.AppendStructs([]bindEvent{}); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if actual := bb.Bytes(); !bytes.Equal(actual, expected) {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+		if actual, expected := countOCFBlocks(bb.Bytes()), 1; actual != expected {
+			t.Errorf("Concurrency: %d; Actual: %v; Expected: %v", concurrency, actual, expected)
+		}
+	}
+}
+
+func TestOCFWriterAppendStructsErrors(t *testing.T) {
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: ioutil.Discard, Schema: bindEventSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.AppendStructs(newBindEvent(1, nil, nil)), "cannot append structs: expected slice")
+
+	// record field having neither struct field nor default value
+	ensureError(t, ocfw.AppendStructs([]struct{ ID int64 }{{1}}), "cannot append Go struct", `field "name"`, "no struct field and schema does not specify default value")
+	ensureError(t, ocfw.AppendStructs([]struct {
+		bindEvent
+		ID string
+	}{{}}), "cannot append Go struct", "cannot bind Avro long to Go string")
+
+	event := newBindEvent(1, nil, nil)
+	event.Kind = "READ"
+	ensureError(t, ocfw.AppendStructs([]bindEvent{event}), `field "kind"`, "ought to be member of symbols")
+
+	ensureError(t, ocfw.AppendStructs([]*bindEvent{nil}), "cannot translate datum to binary", "nil *goavro_test.bindEvent")
+
+	ocfw, err = goavro.NewOCFWriter(goavro.OCFConfig{W: ioutil.Discard, Schema: `"int"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.AppendStructs([]int64{1 << 31}), "cannot encode binary int", "ought to be integer between -2147483648 and 2147483647: 2147483648")
+	ensureError(t, ocfw.AppendStructs([]float64{1.5}), "ought to be integer between")
+
+	ocfw, err = goavro.NewOCFWriter(goavro.OCFConfig{W: ioutil.Discard, Schema: `"float"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.AppendStructs([]float64{0.1}), "cannot encode binary float", "would lose precision")
+	if err = ocfw.AppendStructs([]float64{0.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{0, 2} {
+		ocfw, err = goavro.NewOCFWriter(goavro.OCFConfig{W: ioutil.Discard, Schema: `"int"`, Concurrency: concurrency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		ensureError(t, ocfw.AppendStructs([]int32{1}), "cannot append structs to closed OCFWriter")
+		ensureError(t, ocfw.AppendStructs([]int32{}), "cannot append structs to closed OCFWriter")
+	}
+}
+
+func TestOCFWriterAppendStructsAllocations(t *testing.T) {
+	type record struct {
+		ID    int64
+		Name  string
+		Score float64
+		Kind  int
+		Hash  [2]byte
+		Note  *string
+	}
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: ioutil.Discard, Schema: `{"type":"record","name":"r","fields":[
+		{"name":"id","type":"long"},
+		{"name":"name","type":"string"},
+		{"name":"score","type":"double"},
+		{"name":"kind","type":{"type":"enum","name":"Kind","symbols":["A","B"]}},
+		{"name":"hash","type":{"type":"fixed","name":"Hash","size":2}},
+		{"name":"note","type":["null","string"]}
+	]}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := "note"
+	records := make([]record, 1000)
+	for i := range records {
+		records[i] = record{ID: int64(i), Name: "name", Score: float64(i), Kind: i % 2, Note: &note}
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := ocfw.AppendStructs(records); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// NOTE: Writing the block may allocate, but encoding the records ought
+	// not allocate.
+	if actual, expected := allocs, float64(len(records))/100; actual >= expected {
+		t.Errorf("Actual: %v; Expected: less than %v", actual, expected)
+	}
+}
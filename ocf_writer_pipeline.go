@@ -0,0 +1,373 @@
+package goavro
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ocfWriterBlock is a block of encoded data items submitted to the pipeline,
+// along with its framed output once compressed by a worker.
+type ocfWriterBlock struct {
+	count   int
+	raw     []byte        // encoded data items, released once compressed
+	out     []byte        // block count, block size, compressed block, and sync marker
+	err     error         // compression error
+	done    chan struct{} // closed once out or err is set
+	flushed chan struct{} // when not nil, block is a flush marker rather than data
+
+	// When encode is not nil, the worker encodes count data items using it,
+	// rather than compressing raw, emitting a block every MaxBlockCount items.
+	// A data item which cannot be encoded is dropped, along with the rest of
+	// its block unless dropInvalid, and only its error is kept.
+	encode      func(buf []byte, i int) ([]byte, error)
+	dropInvalid bool  // true when data items were buffered by Write
+	encodeErr   error // first error encoding a data item, which is not fatal
+}
+
+// ocfWriterPipeline compresses blocks in a pool of worker go routines, and
+// writes them to the underlying io.Writer in their original order from a
+// single go routine.
+type ocfWriterPipeline struct {
+	jobs    chan *ocfWriterBlock // blocks to be compressed, for workers
+	ordered chan *ocfWriterBlock // ordered blocks, for output go routine
+	buffers chan []byte          // free list of reusable buffers
+	wg      sync.WaitGroup
+
+	lock      sync.Mutex
+	err       error // first error in block order, after which no more blocks are written
+	encodeErr error // first error encoding a data item in block order since last waitPipeline
+
+	// Total encoded bytes and count of data items encoded by workers, used to
+	// estimate how many data items Write buffers for each block. Protected by
+	// lock.
+	encodedBytes int64
+	encodedItems int64
+}
+
+func (ocfw *OCFWriter) startPipeline(config OCFConfig) {
+	if config.Concurrency <= 1 {
+		return
+	}
+	pending := config.MaxPendingBlocks
+	if pending == 0 {
+		pending = 2 * config.Concurrency
+	}
+	p := &ocfWriterPipeline{
+		jobs:    make(chan *ocfWriterBlock, pending),
+		ordered: make(chan *ocfWriterBlock, pending),
+		buffers: make(chan []byte, 3*(pending+config.Concurrency)),
+	}
+	ocfw.pipeline = p
+
+	p.wg.Add(1 + config.Concurrency)
+	go ocfw.pipelineOutput()
+	for i := 0; i < config.Concurrency; i++ {
+		go ocfw.pipelineWorker()
+	}
+}
+
+// getBuffer returns an empty buffer from the free list, or nil when the free
+// list is empty.
+func (p *ocfWriterPipeline) getBuffer() []byte {
+	select {
+	case buf := <-p.buffers:
+		return buf[:0]
+	default:
+		return nil
+	}
+}
+
+// putBuffer returns buf to the free list, unless the free list is full.
+func (p *ocfWriterPipeline) putBuffer(buf []byte) {
+	if cap(buf) == 0 {
+		return
+	}
+	select {
+	case p.buffers <- buf:
+	default:
+	}
+}
+
+func (p *ocfWriterPipeline) error() error {
+	p.lock.Lock()
+	err := p.err
+	p.lock.Unlock()
+	return err
+}
+
+func (p *ocfWriterPipeline) setError(err error) {
+	p.lock.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.lock.Unlock()
+}
+
+// submitBlock hands the data items encoded in the block buffer to the
+// pipeline as a single block having count items. It blocks while the maximum
+// number of blocks are already pending, and returns the first error that
+// occurred while compressing or writing previously submitted blocks.
+func (ocfw *OCFWriter) submitBlock(count int) error {
+	p := ocfw.pipeline
+	if err := p.error(); err != nil {
+		ocfw.err = err
+		return err
+	}
+	b := &ocfWriterBlock{count: count, raw: ocfw.block, done: make(chan struct{})}
+	ocfw.block = p.getBuffer()
+	p.ordered <- b
+	p.jobs <- b
+	return nil
+}
+
+// submitData hands count data items to the pipeline, to be encoded by a worker
+// using encode as one or more blocks, each having no more than MaxBlockCount
+// items. When dropInvalid, a data item which cannot be encoded is dropped from
+// its block, as Write does, otherwise the entire block is dropped, as Append
+// does. Either way, the error is returned by the following waitPipeline. Like
+// submitBlock, it blocks while the maximum number of blocks are already
+// pending, and returns the first error that occurred while compressing or
+// writing previously submitted blocks.
+func (ocfw *OCFWriter) submitData(count int, dropInvalid bool, encode func([]byte, int) ([]byte, error)) error {
+	p := ocfw.pipeline
+	if err := p.error(); err != nil {
+		ocfw.err = err
+		return err
+	}
+	b := &ocfWriterBlock{count: count, encode: encode, dropInvalid: dropInvalid, done: make(chan struct{})}
+	p.ordered <- b
+	p.jobs <- b
+	return nil
+}
+
+// nativeEncoder returns a function that appends the encoded data item i of
+// data to buf.
+func (ocfw *OCFWriter) nativeEncoder(data []interface{}) func([]byte, int) ([]byte, error) {
+	codec := ocfw.header.codec
+	return func(buf []byte, i int) ([]byte, error) {
+		buf, err := codec.BinaryFromNative(buf, data[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot translate datum to binary: %v; %s", data[i], err)
+		}
+		return buf, nil
+	}
+}
+
+// writePipeline is the Write method for a writer using a pipeline. Rather
+// than encoding the datum, it buffers it, so a worker encodes it along with the
+// other data items of its block.
+func (ocfw *OCFWriter) writePipeline(datum interface{}) error {
+	if len(ocfw.data) == 0 {
+		if ocfw.dataLimit = ocfw.pipelineBlockItems(); ocfw.dataLimit == 0 {
+			// NOTE: Until workers have encoded any data items, estimate the
+			// encoded size of data items from this one. When it cannot be
+			// encoded, it is emitted by itself, so its error is reported in
+			// block order, just like the errors of other data items.
+			if block, err := ocfw.header.codec.BinaryFromNative(ocfw.block[:0], datum); err == nil {
+				ocfw.block = block
+				ocfw.pipeline.recordEncoded(int64(len(block)), 1)
+				ocfw.dataLimit = ocfw.pipelineBlockItems()
+			}
+		}
+	}
+	ocfw.data = append(ocfw.data, datum)
+	if len(ocfw.data) >= ocfw.dataLimit {
+		return ocfw.emitBufferedBlock()
+	}
+	return nil
+}
+
+// pipelineBlockItems returns the number of data items Write buffers before
+// emitting them as a block, which is BlockCount when specified, but no more
+// than the number of data items estimated to encode to BlockSize bytes, based
+// on the data items encoded so far, or 0 when no data items have been encoded.
+func (ocfw *OCFWriter) pipelineBlockItems() int {
+	p := ocfw.pipeline
+	p.lock.Lock()
+	encodedBytes, encodedItems := p.encodedBytes, p.encodedItems
+	p.lock.Unlock()
+	if encodedItems == 0 {
+		return 0
+	}
+
+	limit := MaxBlockCount
+	if ocfw.blockCount > 0 && int64(ocfw.blockCount) < limit {
+		limit = int64(ocfw.blockCount)
+	}
+	if encodedBytes > 0 {
+		// NOTE: Like Write without a pipeline, which emits a block once it
+		// has at least BlockSize bytes, round up.
+		averageSize := float64(encodedBytes) / float64(encodedItems)
+		if estimate := int64(math.Ceil(float64(ocfw.blockSize) / averageSize)); estimate < limit {
+			limit = estimate
+		}
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return int(limit)
+}
+
+// recordEncoded adds to the total encoded bytes and count of data items
+// encoded.
+func (p *ocfWriterPipeline) recordEncoded(encodedBytes, encodedItems int64) {
+	p.lock.Lock()
+	p.encodedBytes += encodedBytes
+	p.encodedItems += encodedItems
+	p.lock.Unlock()
+}
+
+// submitCompressedBlock hands the already compressed block having count items
+// to the pipeline to be written in order, bypassing the workers.
+func (ocfw *OCFWriter) submitCompressedBlock(count int, block []byte) error {
+	p := ocfw.pipeline
+	if err := p.error(); err != nil {
+		ocfw.err = err
+		return err
+	}
+	b := &ocfWriterBlock{count: count, out: appendOCFBlock(p.getBuffer(), count, block, &ocfw.header.syncMarker), done: make(chan struct{})}
+	close(b.done)
+	p.ordered <- b
+	return nil
+}
+
+// waitPipeline blocks until all previously submitted blocks have been written,
+// then returns the first error that occurred while compressing or writing
+// them. Otherwise, it returns the first error encoding a data item submitted
+// since the previous waitPipeline, which, unlike the other errors, does not
+// prevent subsequent blocks from being written.
+func (ocfw *OCFWriter) waitPipeline() error {
+	p := ocfw.pipeline
+	marker := &ocfWriterBlock{flushed: make(chan struct{})}
+	p.ordered <- marker
+	<-marker.flushed
+	if err := p.error(); err != nil {
+		ocfw.err = err
+		return err
+	}
+	p.lock.Lock()
+	err := p.encodeErr
+	p.encodeErr = nil
+	p.lock.Unlock()
+	return err
+}
+
+// stopPipeline waits for all previously submitted blocks to be written, then
+// releases the pipeline go routines.
+func (ocfw *OCFWriter) stopPipeline() {
+	p := ocfw.pipeline
+	close(p.jobs)
+	close(p.ordered)
+	p.wg.Wait()
+}
+
+// pipelineWorker encodes and compresses blocks, and frames them for output.
+func (ocfw *OCFWriter) pipelineWorker() {
+	p := ocfw.pipeline
+	defer p.wg.Done()
+
+	for b := range p.jobs {
+		if b.encode != nil {
+			ocfw.encodeBlocks(b)
+		} else {
+			b.out, b.err = ocfw.compressBlock(p.getBuffer(), b.count, b.raw)
+			p.putBuffer(b.raw)
+			b.raw = nil
+		}
+		close(b.done)
+	}
+}
+
+// encodeBlocks encodes the data items of b, and frames them for output as one
+// or more blocks, each having no more than MaxBlockCount items. Just as
+// Append, when b has no data items, it frames a single empty block.
+func (ocfw *OCFWriter) encodeBlocks(b *ocfWriterBlock) {
+	p := ocfw.pipeline
+	raw := p.getBuffer()
+	out := p.getBuffer()
+	var encodedBytes, encodedItems int64
+	var items int
+	var err error
+
+	for i := 0; i < b.count && err == nil; i++ {
+		encoded, encodeErr := b.encode(raw, i)
+		if encodeErr != nil {
+			if b.encodeErr == nil {
+				b.encodeErr = encodeErr
+			}
+			if b.dropInvalid {
+				continue // raw still has its original length
+			}
+			// NOTE: Like Append, drop the remaining data items, along with
+			// those of the block being encoded.
+			raw, items = raw[:0], 0
+			break
+		}
+		raw = encoded
+		encodedItems++
+		if items++; int64(items) >= MaxBlockCount {
+			encodedBytes += int64(len(raw))
+			out, err = ocfw.compressBlock(out, items, raw)
+			raw, items = raw[:0], 0
+		}
+	}
+	if err == nil && (items > 0 || (b.count == 0 && !b.dropInvalid)) {
+		encodedBytes += int64(len(raw))
+		out, err = ocfw.compressBlock(out, items, raw)
+	}
+	p.putBuffer(raw)
+	if err != nil {
+		p.putBuffer(out)
+		b.err = err
+		return
+	}
+	p.recordEncoded(encodedBytes, encodedItems)
+	b.out = out
+}
+
+// compressBlock compresses raw, and appends it to out, framed as a block
+// having count items.
+func (ocfw *OCFWriter) compressBlock(out []byte, count int, raw []byte) ([]byte, error) {
+	p := ocfw.pipeline
+	compressed, err := ocfw.compressor.Compress(p.getBuffer(), raw)
+	if err != nil {
+		return out, fmt.Errorf("cannot compress block: %s", err)
+	}
+	out = appendOCFBlock(out, count, compressed, &ocfw.header.syncMarker)
+	p.putBuffer(compressed)
+	return out, nil
+}
+
+// pipelineOutput writes compressed blocks to the underlying io.Writer in the
+// order they were submitted. Once any block fails to be compressed or written,
+// no subsequent blocks are written, so the first error in block order is always
+// the one reported. Errors encoding data items are likewise kept in block
+// order, but blocks continue to be written.
+func (ocfw *OCFWriter) pipelineOutput() {
+	p := ocfw.pipeline
+	defer p.wg.Done()
+
+	for b := range p.ordered {
+		if b.flushed != nil {
+			close(b.flushed)
+			continue
+		}
+		<-b.done
+		if p.error() == nil {
+			if b.err != nil {
+				p.setError(b.err)
+			} else if _, err := ocfw.iow.Write(b.out); err != nil {
+				p.setError(err)
+			} else if b.encodeErr != nil {
+				p.lock.Lock()
+				if p.encodeErr == nil {
+					p.encodeErr = b.encodeErr
+				}
+				p.lock.Unlock()
+			}
+		}
+		p.putBuffer(b.out)
+		b.out = nil
+	}
+}
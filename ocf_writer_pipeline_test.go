@@ -0,0 +1,199 @@
+package goavro_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+// failingCompressor is a Compressor used to test compression errors. It fails
+// to compress any block whose first byte is at least 0x70, which is the first
+// byte of the encoded long values 56 through 63.
+type failingCompressor struct{}
+
+func (failingCompressor) Compress(dst, src []byte) ([]byte, error) {
+	if len(src) > 0 && src[0] >= 0x70 {
+		return nil, fmt.Errorf("cannot compress first byte: %#x", src[0])
+	}
+	return append(dst, src...), nil
+}
+
+func (failingCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	return append(dst, src...), nil
+}
+
+func init() {
+	if err := goavro.RegisterCompressor("test-failing", failingCompressor{}); err != nil {
+		panic(err)
+	}
+}
+
+func TestOCFWriterConcurrency(t *testing.T) {
+	for _, compressionName := range []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		for _, config := range []goavro.OCFConfig{
+			{Concurrency: 2},
+			{Concurrency: 4, MaxPendingBlocks: 1},
+			{Concurrency: 8, MaxPendingBlocks: 100},
+		} {
+			config.CompressionName = compressionName
+			config.BlockCount = 37
+			testOCFWriterWrite(t, config, 10000, 271)
+		}
+	}
+	// values 0 through 63 encode to 1 byte each, so emits a block every 8 items
+	testOCFWriterWrite(t, goavro.OCFConfig{BlockSize: 8, Concurrency: 4}, 64, 8)
+}
+
+func TestOCFWriterConcurrencyAppend(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               bb,
+		Schema:          `"long"`,
+		CompressionName: goavro.CompressionDeflateLabel,
+		Concurrency:     4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mix of buffered writes and appended blocks, reusing the appended slice
+	// while its data items may still be pending
+	var value int64
+	buf := make([]interface{}, 7)
+	for i := 0; i < 100; i++ {
+		if err = ocfw.Write(value); err != nil {
+			t.Fatal(err)
+		}
+		value++
+		data := buf[:1+i%7]
+		for j := range data {
+			data[j] = value
+			value++
+		}
+		if err = ocfw.Append(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	values := readOCFLongs(t, bb.Bytes())
+	if actual, expected := int64(len(values)), value; actual != expected {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	for i, value := range values {
+		if actual, expected := value, int64(i); actual != expected {
+			t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+}
+
+// TestOCFWriterConcurrencyFirstError ensures the error reported is always the
+// one from the first block in order which cannot be compressed, and that no
+// blocks after it are written.
+func TestOCFWriterConcurrencyFirstError(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		bb := new(bytes.Buffer)
+		ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+			W:               bb,
+			Schema:          `"long"`,
+			CompressionName: "test-failing",
+			BlockCount:      1,
+			Concurrency:     8,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 64; j++ {
+			if err = ocfw.Write(int64(j)); err != nil {
+				break // error from a previous block may be returned
+			}
+		}
+		ensureError(t, ocfw.Close(), "cannot compress block", "0x70")
+
+		values := readOCFLongs(t, bb.Bytes())
+		if actual, expected := len(values), 56; actual != expected {
+			t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+}
+
+// TestOCFWriterConcurrencyEncodeError ensures a data item which cannot be
+// encoded by a worker is dropped from its block, without dropping any other
+// data items, and that the first such error in order is returned by Flush.
+func TestOCFWriterConcurrencyEncodeError(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		bb := new(bytes.Buffer)
+		ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+			W:           bb,
+			Schema:      `"long"`,
+			BlockCount:  5,
+			Concurrency: 4,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected []int64
+		for j := 0; j < 30; j++ {
+			var datum interface{} = int64(j)
+			if j == 0 || j == 12 || j == 20 {
+				datum = fmt.Sprintf("bad-%d", j)
+			} else {
+				expected = append(expected, int64(j))
+			}
+			if err = ocfw.Write(datum); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ensureError(t, ocfw.Flush(), "cannot translate datum to binary", "bad-0")
+		if err = ocfw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Like Append without a pipeline, a data item which cannot be encoded
+		// drops its entire block.
+		if err = ocfw.Append([]interface{}{int64(30), "bad-31", int64(32)}); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Append([]interface{}{int64(33)}); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, 33)
+		ensureError(t, ocfw.Close(), "cannot translate datum to binary", "bad-31")
+
+		values := readOCFLongs(t, bb.Bytes())
+		if actual, expected := fmt.Sprint(values), fmt.Sprint(expected); actual != expected {
+			t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+}
+
+func TestOCFWriterConcurrencyWriteError(t *testing.T) {
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:           &failingWriter{remaining: 3}, // header and two blocks
+		Schema:      `"long"`,
+		BlockCount:  1,
+		Concurrency: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < 10; j++ {
+		if err = ocfw.Write(int64(j)); err != nil {
+			break
+		}
+	}
+	ensureError(t, ocfw.Flush(), "failing writer")
+	ensureError(t, ocfw.Write(int64(13)), "failing writer")
+	ensureError(t, ocfw.Close(), "failing writer")
+}
+
+func TestOCFWriterConcurrencyInvalidConfig(t *testing.T) {
+	_, err := goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, Concurrency: -1})
+	ensureError(t, err, "cannot create OCFWriter", "Concurrency")
+
+	_, err = goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, MaxPendingBlocks: -1})
+	ensureError(t, err, "cannot create OCFWriter", "MaxPendingBlocks")
+}
@@ -2,8 +2,12 @@ package goavro_test
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/karrick/goavro"
@@ -287,3 +291,754 @@ func TestOCFWriterAppendSomeItemsToSomeItems(t *testing.T) {
 		t.Errorf("Actual: %v; Expected: %v", actual, expected)
 	}
 }
+
+func TestOCFWriterMetaData(t *testing.T) {
+	_, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:        new(bytes.Buffer),
+		Schema:   `{"type":"long"}`,
+		MetaData: map[string][]byte{"avro.custom": []byte("value")},
+	})
+	ensureError(t, err, "cannot create OCFWriter", "reserved metadata key", "avro.custom")
+
+	testPathname := "fixtures/temp5.avro"
+	fh, err := os.OpenFile(testPathname, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               fh,
+		Schema:          `{"type":"long"}`,
+		CompressionName: goavro.CompressionDeflateLabel,
+		MetaData:        map[string][]byte{"producer": []byte("test"), "partition": []byte("13")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Append([]int64{13, 42}); err != nil {
+		t.Fatal(err)
+	}
+	if err = fh.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// append to existing file, requesting different metadata
+	fh, err = os.OpenFile(testPathname, os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ocfw, err = goavro.NewOCFWriter(goavro.OCFConfig{
+		W:        fh,
+		MetaData: map[string][]byte{"producer": []byte("other")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := string(ocfw.MetaData()["producer"]), "test"; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if err = ocfw.Append([]int64{-10}); err != nil {
+		t.Fatal(err)
+	}
+	if err = fh.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fh, err = os.Open(testPathname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(ioc io.Closer) {
+		if err := ioc.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}(fh)
+	ocfr, err := goavro.NewOCFReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := ocfr.MetaData()
+	if actual, expected := len(metadata), 2; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := string(metadata["producer"]), "test"; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := string(metadata["partition"]), "13"; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	var count int
+	for ocfr.Scan() {
+		if _, err := ocfr.Read(); err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := count, 3; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// failingWriter is an io.Writer that accepts the specified number of writes,
+// then returns an error for every subsequent write.
+type failingWriter struct {
+	bytes.Buffer
+	remaining int
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if fw.remaining == 0 {
+		return 0, errors.New("failing writer")
+	}
+	fw.remaining--
+	return fw.Buffer.Write(p)
+}
+
+// countOCFBlocks returns the number of blocks in the OCF in buf, by counting
+// the occurrences of its sync marker, which also terminates its header.
+func countOCFBlocks(buf []byte) int {
+	return bytes.Count(buf, buf[len(buf)-16:]) - 1
+}
+
+// readOCFLongs returns the long values stored in the OCF in buf.
+func readOCFLongs(t *testing.T, buf []byte) []int64 {
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []int64
+	for ocfr.Scan() {
+		value, err := ocfr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, value.(int64))
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func testOCFWriterWrite(t *testing.T, config goavro.OCFConfig, count int, expectedBlocks int) {
+	bb := new(bytes.Buffer)
+	config.W = bb
+	config.Schema = `{"type":"long"}`
+	ocfw, err := goavro.NewOCFWriter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		if err = ocfw.Write(int64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, expected := countOCFBlocks(bb.Bytes()), expectedBlocks; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	values := readOCFLongs(t, bb.Bytes())
+	if actual, expected := len(values), count; actual != expected {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	for i, value := range values {
+		if actual, expected := value, int64(i); actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+}
+
+func TestOCFWriterWrite(t *testing.T) {
+	// everything fits in a single block
+	testOCFWriterWrite(t, goavro.OCFConfig{}, 10, 1)
+	// emits a block every BlockCount items, and the remaining items on Close
+	testOCFWriterWrite(t, goavro.OCFConfig{BlockCount: 3}, 10, 4)
+	testOCFWriterWrite(t, goavro.OCFConfig{BlockCount: 5, CompressionName: goavro.CompressionDeflateLabel}, 10, 2)
+	// values 0 through 63 encode to 1 byte each, so emits a block every 8 items
+	testOCFWriterWrite(t, goavro.OCFConfig{BlockSize: 8}, 64, 8)
+}
+
+func TestOCFWriterWriteMaxBlockCount(t *testing.T) {
+	defer func(max int64) { goavro.MaxBlockCount = max }(goavro.MaxBlockCount)
+	goavro.MaxBlockCount = 4
+
+	testOCFWriterWrite(t, goavro.OCFConfig{}, 10, 3)
+	testOCFWriterWrite(t, goavro.OCFConfig{BlockCount: 100}, 10, 3)
+}
+
+func TestOCFWriterWriteInvalidConfig(t *testing.T) {
+	_, err := goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, BlockCount: -1})
+	ensureError(t, err, "cannot create OCFWriter", "BlockCount")
+
+	_, err = goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, BlockSize: -1})
+	ensureError(t, err, "cannot create OCFWriter", "BlockSize")
+}
+
+func TestOCFWriterWriteCannotEncode(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: bb, Schema: `"long"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Write(int64(13)); err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.Write("not a long"), "cannot translate datum to binary")
+	if err = ocfw.Write(int64(42)); err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	values := readOCFLongs(t, bb.Bytes())
+	if actual, expected := len(values), 2; actual != expected {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := values[0], int64(13); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := values[1], int64(42); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestOCFWriterWriteDeferredError(t *testing.T) {
+	// header write succeeds, but first block write fails
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &failingWriter{remaining: 1}, Schema: `"long"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Write(int64(13)); err != nil {
+		t.Fatal(err) // buffered, so no error yet
+	}
+	ensureError(t, ocfw.Close(), "failing writer")
+	if err = ocfw.Close(); err != nil {
+		t.Errorf("Actual: %v; Expected: %v", err, nil) // already closed
+	}
+
+	// error from block emitted by Write is returned by subsequent calls
+	ocfw, err = goavro.NewOCFWriter(goavro.OCFConfig{W: &failingWriter{remaining: 1}, Schema: `"long"`, BlockCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.Write(int64(13)), "failing writer")
+	ensureError(t, ocfw.Write(int64(42)), "failing writer")
+	ensureError(t, ocfw.Flush(), "failing writer")
+	ensureError(t, ocfw.Append([]interface{}{int64(42)}), "failing writer")
+	ensureError(t, ocfw.Close(), "failing writer")
+}
+
+func TestOCFWriterWriteCompressionError(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: bb, Schema: `"long"`, CompressionName: "test-failing", BlockCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []int64{1, 2, 56} {
+		if err = ocfw.Write(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// block starting with 56 cannot be compressed
+	ensureError(t, ocfw.Write(int64(3)), "cannot compress block", "0x70")
+
+	// error is returned by subsequent calls, rather than discarding the
+	// data items of the block and accepting more
+	ensureError(t, ocfw.Write(int64(4)), "cannot compress block")
+	ensureError(t, ocfw.Flush(), "cannot compress block")
+	ensureError(t, ocfw.Append([]interface{}{int64(5)}), "cannot compress block")
+	ensureError(t, ocfw.Close(), "cannot compress block")
+
+	if actual, expected := readOCFLongs(t, bb.Bytes()), []int64{1, 2}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestOCFWriterWriteAfterClose(t *testing.T) {
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.Write(int64(13)), "closed")
+	ensureError(t, ocfw.Flush(), "closed")
+	ensureError(t, ocfw.Append([]interface{}{int64(13)}), "closed")
+}
+
+func TestOCFWriterWriteThenAppend(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: bb, Schema: `"long"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Write(int64(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Append([]interface{}{int64(1), int64(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Write(int64(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, expected := countOCFBlocks(bb.Bytes()), 3; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	values := readOCFLongs(t, bb.Bytes())
+	for i, value := range values {
+		if actual, expected := value, int64(i); actual != expected {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+	if actual, expected := len(values), 4; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// copyOCFBlocks copies every block of the OCF in buf to a new OCF using the
+// specified compression algorithm and concurrency, and returns the new OCF.
+func copyOCFBlocks(t *testing.T, buf []byte, compressionName string, concurrency int) []byte {
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               bb,
+		Codec:           ocfr.Codec(),
+		CompressionName: compressionName,
+		Concurrency:     concurrency,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		block, err := ocfr.NextBlock()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.AppendRawBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+func TestOCFWriterAppendRawBlock(t *testing.T) {
+	compressionNames := []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel, goavro.CompressionZstandardLabel}
+	for _, from := range compressionNames {
+		buf := newOCFOfLongs(t, 1000, 37, from)
+		for _, to := range compressionNames {
+			for _, concurrency := range []int{0, 4} {
+				copied := copyOCFBlocks(t, buf, to, concurrency)
+				if actual, expected := countOCFBlocks(copied), 28; actual != expected {
+					t.Errorf("%s -> %s: Actual: %v; Expected: %v", from, to, actual, expected)
+				}
+				values := readOCFLongs(t, copied)
+				if actual, expected := len(values), 1000; actual != expected {
+					t.Fatalf("%s -> %s: Actual: %v; Expected: %v", from, to, actual, expected)
+				}
+				for i, value := range values {
+					if actual, expected := value, int64(i); actual != expected {
+						t.Fatalf("%s -> %s: Actual: %v; Expected: %v", from, to, actual, expected)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestOCFWriterAppendRawBlockVerbatim ensures a block is copied without being
+// decompressed when the compression algorithms match, and is decompressed
+// when they do not.
+func TestOCFWriterAppendRawBlockVerbatim(t *testing.T) {
+	block := goavro.OCFBlock{Count: 1, Data: []byte("not deflate data"), CompressionName: goavro.CompressionDeflateLabel}
+
+	bb := new(bytes.Buffer)
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: bb, Schema: `"long"`, CompressionName: goavro.CompressionDeflateLabel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ocfw.AppendRawBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(bb.Bytes(), block.Data) {
+		t.Errorf("Actual: %v; Expected: %v", false, true)
+	}
+
+	ocfw, err = goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, CompressionName: goavro.CompressionSnappyLabel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.AppendRawBlock(block), "cannot append raw block", "cannot decompress")
+}
+
+func TestOCFWriterAppendRawBlockWithWrite(t *testing.T) {
+	buf := newOCFOfLongs(t, 10, 5, goavro.CompressionDeflateLabel)
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ocfr.NextBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{0, 4} {
+		bb := new(bytes.Buffer)
+		ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: bb, Schema: `"long"`, Concurrency: concurrency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// buffered data items are written before the raw block
+		if err = ocfw.Write(int64(13)); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.AppendRawBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Write(int64(42)); err != nil {
+			t.Fatal(err)
+		}
+		if err = ocfw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := readOCFLongs(t, bb.Bytes()), []int64{13, 0, 1, 2, 3, 4, 42}; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+}
+
+func TestOCFWriterAppendRawBlockErrors(t *testing.T) {
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.AppendRawBlock(goavro.OCFBlock{CompressionName: goavro.CompressionNullLabel}), "cannot append raw block", "not greater than 0")
+	ensureError(t, ocfw.AppendRawBlock(goavro.OCFBlock{Count: goavro.MaxBlockCount + 1, CompressionName: goavro.CompressionNullLabel}), "cannot append raw block", "MaxBlockCount")
+	ensureError(t, ocfw.AppendRawBlock(goavro.OCFBlock{Count: 1, CompressionName: "no-such-compression"}), "cannot append raw block", "unrecognized compression")
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, ocfw.AppendRawBlock(goavro.OCFBlock{Count: 1, Data: []byte{2}, CompressionName: goavro.CompressionNullLabel}), "cannot append raw block", "closed")
+}
+
+func TestOCFReaderNextBlockErrors(t *testing.T) {
+	buf := newOCFOfLongs(t, 10, 5, goavro.CompressionNullLabel)
+
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ocfr.Scan() {
+		t.Fatal(ocfr.Err())
+	}
+	if _, err = ocfr.Read(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ocfr.NextBlock()
+	ensureError(t, err, "cannot read raw block", "data items remain")
+
+	ocfr, err = goavro.NewOCFReaderWithConfig(bytes.NewReader(buf), goavro.OCFReaderConfig{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ocfr.Close()
+	_, err = ocfr.NextBlock()
+	ensureError(t, err, "cannot read raw block", "Concurrency")
+}
+
+// memFile is an in-memory io.ReadWriteSeeker, used to test appending to an
+// OCF in something other than an *os.File.
+type memFile struct {
+	buf    []byte
+	offset int64
+}
+
+func (mf *memFile) Read(p []byte) (int, error) {
+	if mf.offset >= int64(len(mf.buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, mf.buf[mf.offset:])
+	mf.offset += int64(n)
+	return n, nil
+}
+
+func (mf *memFile) Write(p []byte) (int, error) {
+	if end := mf.offset + int64(len(p)); end > int64(len(mf.buf)) {
+		mf.buf = append(mf.buf, make([]byte, end-int64(len(mf.buf)))...)
+	}
+	n := copy(mf.buf[mf.offset:], p)
+	mf.offset += int64(n)
+	return n, nil
+}
+
+func (mf *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += mf.offset
+	case io.SeekEnd:
+		offset += int64(len(mf.buf))
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	mf.offset = offset
+	return offset, nil
+}
+
+func (mf *memFile) Truncate(size int64) error {
+	if size < 0 || size > int64(len(mf.buf)) {
+		return fmt.Errorf("invalid size: %d", size)
+	}
+	mf.buf = mf.buf[:size]
+	return nil
+}
+
+// unseekableFile is an io.ReadWriteSeeker which cannot seek, like a pipe.
+type unseekableFile struct {
+	bytes.Buffer
+}
+
+func (*unseekableFile) Seek(int64, int) (int64, error) {
+	return 0, errors.New("illegal seek")
+}
+
+// appendOCFLongs writes the long values from through to-1 to the OCF in
+// config.W, appending to any existing OCF.
+func appendOCFLongs(t *testing.T, config goavro.OCFConfig, from, to int) {
+	ocfw, err := goavro.NewOCFWriter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := from; i < to; i++ {
+		if err = ocfw.Write(int64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ocfw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOCFWriterAppendToReadWriteSeeker(t *testing.T) {
+	mf := new(memFile)
+	appendOCFLongs(t, goavro.OCFConfig{W: mf, Schema: `"long"`, CompressionName: goavro.CompressionDeflateLabel}, 0, 10)
+
+	// schema and compression of existing OCF are used
+	mf.offset = 0
+	appendOCFLongs(t, goavro.OCFConfig{W: mf, Schema: `"int"`, CompressionName: goavro.CompressionSnappyLabel}, 10, 20)
+
+	// position of existing OCF is irrelevant
+	mf.offset = 7
+	appendOCFLongs(t, goavro.OCFConfig{W: mf, Append: true}, 20, 30)
+
+	if actual, expected := readOCFLongs(t, mf.buf), expectedLongs(30); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := countOCFBlocks(mf.buf), 3; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestOCFWriterAppendMode(t *testing.T) {
+	// empty io.ReadWriteSeeker receives new OCF header
+	mf := new(memFile)
+	appendOCFLongs(t, goavro.OCFConfig{W: mf, Schema: `"long"`, Append: true}, 0, 10)
+	if actual, expected := readOCFLongs(t, mf.buf), expectedLongs(10); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	_, err := goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, Append: true})
+	ensureError(t, err, "cannot create OCFWriter in Append mode", "not an io.ReadWriteSeeker")
+
+	_, err = goavro.NewOCFWriter(goavro.OCFConfig{W: new(unseekableFile), Schema: `"long"`, Append: true})
+	ensureError(t, err, "cannot create OCFWriter in Append mode", "illegal seek")
+
+	// without Append mode, writer which cannot seek receives new OCF header
+	uf := new(unseekableFile)
+	appendOCFLongs(t, goavro.OCFConfig{W: uf, Schema: `"long"`}, 0, 10)
+	if actual, expected := readOCFLongs(t, uf.Bytes()), expectedLongs(10); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// readOCFData returns the data items stored in the OCF in buf.
+func readOCFData(t *testing.T, buf []byte) []interface{} {
+	ocfr, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, datum)
+	}
+	if err = ocfr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestOCFWriterTruncateTornBlock truncates fixtures at every offset following
+// their header, and ensures data items may be appended to each, losing only
+// the data items of the torn block.
+func TestOCFWriterTruncateTornBlock(t *testing.T) {
+	for _, pathname := range []string{"fixtures/weather-null.avro", "fixtures/weather-deflate.avro"} {
+		buf, err := ioutil.ReadFile(pathname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		syncs := ocfSyncOffsets(buf)
+		datum := readOCFData(t, buf)[0]
+
+		for offset := syncs[0] + 16; offset <= len(buf); offset++ {
+			// end of final complete block, and its count of data items
+			var tail int
+			for _, sync := range syncs {
+				if sync+16 <= offset {
+					tail = sync + 16
+				}
+			}
+			items := len(readOCFData(t, buf[:tail]))
+
+			mf := &memFile{buf: append([]byte(nil), buf[:offset]...)}
+			_, err := goavro.NewOCFWriter(goavro.OCFConfig{W: mf})
+			if offset == tail {
+				if err != nil {
+					t.Fatalf("%s; offset: %d; %s", pathname, offset, err)
+				}
+			} else {
+				ensureError(t, err, "cannot create OCFWriter")
+			}
+
+			mf.offset = 0
+			ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: mf, TruncateTornBlock: true})
+			if err != nil {
+				t.Fatalf("%s; offset: %d; %s", pathname, offset, err)
+			}
+			if actual, expected := ocfw.TruncatedBytes(), int64(offset-tail); actual != expected {
+				t.Errorf("%s; offset: %d; Actual: %v; Expected: %v", pathname, offset, actual, expected)
+			}
+			if err = ocfw.Append([]interface{}{datum}); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := len(readOCFData(t, mf.buf)), items+1; actual != expected {
+				t.Errorf("%s; offset: %d; Actual: %v; Expected: %v", pathname, offset, actual, expected)
+			}
+		}
+	}
+}
+
+func TestOCFWriterTruncateTornBlockErrors(t *testing.T) {
+	buf := newOCFOfLongs(t, 20, 10, goavro.CompressionNullLabel)
+	torn := buf[:len(buf)-5]
+
+	// complete but corrupt block is not truncated
+	corrupt := append([]byte(nil), buf...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	_, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &memFile{buf: corrupt}, TruncateTornBlock: true})
+	ensureError(t, err, "cannot create OCFWriter", "sync marker mismatch")
+
+	// writer which cannot truncate
+	_, err = goavro.NewOCFWriter(goavro.OCFConfig{W: struct{ io.ReadWriteSeeker }{&memFile{buf: torn}}, TruncateTornBlock: true})
+	ensureError(t, err, "cannot create OCFWriter", "cannot truncate torn block", "does not implement Truncate")
+
+	// file is truncated
+	createTestFile(t, "fixtures/temp3.avro", torn)
+	fh, err := os.OpenFile("fixtures/temp3.avro", os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(ioc io.Closer) {
+		if err := ioc.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}(fh)
+	appendOCFLongs(t, goavro.OCFConfig{W: fh, TruncateTornBlock: true}, 10, 20)
+	written, err := ioutil.ReadFile("fixtures/temp3.avro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := readOCFLongs(t, written), expectedLongs(20); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+// writeOCFDeterministic returns an OCF holding a few long values, written
+// using the specified configuration.
+func writeOCFDeterministic(t *testing.T, config goavro.OCFConfig) []byte {
+	bb := new(bytes.Buffer)
+	config.W = bb
+	if config.Schema == "" {
+		config.Schema = `"long"`
+	}
+	config.MetaData = map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": []byte("3"), "d": []byte("4"), "e": []byte("5")}
+	appendOCFLongs(t, config, 0, 100)
+	return bb.Bytes()
+}
+
+func TestOCFWriterSyncMarker(t *testing.T) {
+	marker := []byte("0123456789abcdef")
+	first := writeOCFDeterministic(t, goavro.OCFConfig{SyncMarker: marker, CompressionName: goavro.CompressionDeflateLabel})
+	for i := 0; i < 10; i++ {
+		if actual, expected := writeOCFDeterministic(t, goavro.OCFConfig{SyncMarker: marker, CompressionName: goavro.CompressionDeflateLabel}), first; !bytes.Equal(actual, expected) {
+			t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+		}
+	}
+	if actual, expected := first[len(first)-16:], marker; !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := readOCFLongs(t, first), expectedLongs(100); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	_, err := goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, SyncMarker: marker[:15]})
+	ensureError(t, err, "cannot create OCFWriter", "SyncMarker length is not 16: 15")
+
+	_, err = goavro.NewOCFWriter(goavro.OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, SyncMarker: marker, SyncMarkerSeed: "seed"})
+	ensureError(t, err, "cannot create OCFWriter", "both SyncMarker and SyncMarkerSeed")
+}
+
+func TestOCFWriterSyncMarkerSeed(t *testing.T) {
+	first := writeOCFDeterministic(t, goavro.OCFConfig{SyncMarkerSeed: "seed"})
+	if actual, expected := writeOCFDeterministic(t, goavro.OCFConfig{SyncMarkerSeed: "seed"}), first; !bytes.Equal(actual, expected) {
+		t.Fatalf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := readOCFLongs(t, first), expectedLongs(100); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// sync marker depends upon both seed and schema
+	marker := first[len(first)-16:]
+	for _, config := range []goavro.OCFConfig{
+		{SyncMarkerSeed: "other seed"},
+		{SyncMarkerSeed: "seed", Schema: `{"type":"long"}`},
+		{}, // random
+	} {
+		other := writeOCFDeterministic(t, config)
+		if bytes.Equal(other[len(other)-16:], marker) {
+			t.Errorf("%+v: Actual: %v; Expected: different sync marker", config, marker)
+		}
+	}
+}
@@ -4,7 +4,17 @@ import (
 	"fmt"
 )
 
-func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+// recordField describes a field of a record, so Go types may be bound to the
+// record codec.
+type recordField struct {
+	name         string
+	codec        *Codec
+	defaultValue interface{} // only valid when hasDefault
+	hasDefault   bool
+	aliases      []string
+}
+
+func makeRecordCodec(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
 	// NOTE: To support recursive data types, create the codec and register it
 	// using the specified name, and fill in the codec functions later.
 	c, err := registerNewCodec(st, schemaMap, enclosingNamespace)
@@ -17,14 +27,19 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		return nil, fmt.Errorf("Record %q ought to have fields key", c.typeName)
 	}
 	fieldSchemas, ok := fields.([]interface{})
-	if !ok || len(fieldSchemas) == 0 {
-		return nil, fmt.Errorf("Record %q fields ought to be non-empty array: %v", c.typeName, fields)
+	if !ok {
+		return nil, fmt.Errorf("Record %q fields ought to be array: %v", c.typeName, fields)
 	}
+	// NOTE: Records may have no fields, so ensure fields is not nil, which
+	// identifies the codec as a record.
+	c.fields = make([]recordField, 0, len(fieldSchemas))
 
 	codecFromFieldName := make(map[string]*Codec)
 	codecFromIndex := make([]*Codec, len(fieldSchemas))
 	nameFromIndex := make([]string, len(fieldSchemas))
 	defaultValueFromName := make(map[string]interface{}, len(fieldSchemas))
+	decodedDefaultValueFromName := make(map[string]interface{}, len(fieldSchemas)) // as returned by decoders
+	wrapDecoded := unionWrapper(config.UnionDecoding)
 
 	for i, fieldSchema := range fieldSchemas {
 		fieldSchemaMap, ok := fieldSchema.(map[string]interface{})
@@ -35,7 +50,7 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		// NOTE: field names are not registered in the symbol table, because
 		// field names are not individually addressable codecs.
 
-		fieldCodec, err := buildCodecForTypeDescribedByMap(st, c.typeName.namespace, fieldSchemaMap)
+		fieldCodec, err := buildCodecForTypeDescribedByMap(st, config, c.typeName.namespace, fieldSchemaMap)
 		if err != nil {
 			return nil, fmt.Errorf("Record %q field %d ought to be valid Avro named type: %s", c.typeName, i+1, err)
 		}
@@ -52,11 +67,15 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		}
 
 		if defaultValue, ok := fieldSchemaMap["default"]; ok {
+			decodedDefaultValue := defaultValue
 			// if codec is union, then default value ought to encode using first schema in union
 			if fieldCodec.typeName.short() == "union" {
 				// NOTE: To support record field default values, union schema
 				// set to the type name of first member
 				defaultValue = Union(fieldCodec.schema, defaultValue)
+				if defaultValue != nil {
+					decodedDefaultValue = wrapDecoded(fieldCodec.schema, decodedDefaultValue)
+				}
 			}
 			// attempt to encode default value using codec
 			_, err = fieldCodec.binaryFromNative(nil, defaultValue)
@@ -64,11 +83,14 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 				return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
 			}
 			defaultValueFromName[fieldName] = defaultValue
+			decodedDefaultValueFromName[fieldName] = decodedDefaultValue
 		}
 
 		nameFromIndex[i] = fieldName
 		codecFromIndex[i] = fieldCodec
 		codecFromFieldName[fieldName] = fieldCodec
+		defaultValue, hasDefault := defaultValueFromName[fieldName]
+		c.fields = append(c.fields, recordField{name: fieldName, codec: fieldCodec, defaultValue: defaultValue, hasDefault: hasDefault, aliases: aliasesFromSchemaMap(fieldSchemaMap)})
 	}
 
 	c.binaryFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
@@ -128,7 +150,7 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		if actual, expected := len(mapValues), len(codecFromFieldName); actual != expected {
 			// set missing field keys to their respective default values, then
 			// re-check number of keys
-			for fieldName, defaultValue := range defaultValueFromName {
+			for fieldName, defaultValue := range decodedDefaultValueFromName {
 				if _, ok := mapValues[fieldName]; !ok {
 					mapValues[fieldName] = defaultValue
 				}
@@ -148,23 +170,36 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		if !ok {
 			return nil, fmt.Errorf("cannot encode textual record %q: expected map[string]interface{}; received: %T", c.typeName, datum)
 		}
-		destMap := make(map[string]interface{}, len(codecFromIndex))
-		for fieldName := range codecFromFieldName {
+
+		buf = append(buf, '{')
+
+		// records encoded in order fields were defined in schema, so output is
+		// byte for byte identical each time the same datum is encoded
+		for i, fieldCodec := range codecFromIndex {
+			fieldName := nameFromIndex[i]
+
 			fieldValue, ok := sourceMap[fieldName]
 			if !ok {
-				defaultValue, ok := defaultValueFromName[fieldName]
-				if !ok {
+				if fieldValue, ok = defaultValueFromName[fieldName]; !ok {
 					return nil, fmt.Errorf("cannot encode textual record %q field %q: schema does not specify default value and no value provided", c.typeName, fieldName)
 				}
-				fieldValue = defaultValue
 			}
-			destMap[fieldName] = fieldValue
+
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			// only fails when given non string, so elide error checking
+			buf, _ = stringTextualFromNative(buf, fieldName)
+			buf = append(buf, ':')
+
+			var err error
+			buf, err = fieldCodec.textualFromNative(buf, fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode textual record %q field %q: value does not match its schema: %s", c.typeName, fieldName, err)
+			}
 		}
-		datum = destMap
-		// NOTE: Setting `defaultCodec == nil` instructs genericMapTextEncoder
-		// to return an error when a field name is not found in the
-		// codecFromFieldName map.
-		return genericMapTextEncoder(buf, datum, nil, codecFromFieldName)
+
+		return append(buf, '}'), nil
 	}
 
 	return c, nil
@@ -18,8 +18,8 @@ func TestRecordName(t *testing.T) {
 
 func TestRecordFields(t *testing.T) {
 	testSchemaInvalid(t, `{"type":"record","name":"r1"}`, `Record "r1" ought to have fields key`)
-	testSchemaInvalid(t, `{"type":"record","name":"r1","fields":3}`, `Record "r1" fields ought to be non-empty array`)
-	testSchemaInvalid(t, `{"type":"record","name":"r1","fields":[]}`, `Record "r1" fields ought to be non-empty array`)
+	testSchemaInvalid(t, `{"type":"record","name":"r1","fields":3}`, `Record "r1" fields ought to be array`)
+	testSchemaValid(t, `{"type":"record","name":"r1","fields":[]}`)
 }
 
 func TestRecordFieldInvalid(t *testing.T) {
@@ -389,6 +389,15 @@ func TestRecordTextCodecPass(t *testing.T) {
 	testTextDecodePass(t, `{"name":"r1","type":"record","fields":[{"name":"string","type":"string"},{"name":"bytes","type":"bytes"}]}`, map[string]interface{}{"string": silly, "bytes": []byte(silly)}, []byte(` { "string" : "\u0001\u2318 " , "bytes" : "\u0001\u00E2\u008C\u0098 " }`))
 }
 
+func TestRecordTextualFieldOrder(t *testing.T) {
+	schema := `{"name":"r1","type":"record","fields":[{"name":"zulu","type":"int"},{"name":"alpha","type":"int"},{"name":"mike","type":"int","default":3}]}`
+	datum := map[string]interface{}{"alpha": 1, "zulu": 2}
+	// encode several times to detect any non-deterministic field ordering
+	for i := 0; i < 10; i++ {
+		testTextEncodePass(t, schema, datum, []byte(`{"zulu":2,"alpha":1,"mike":3}`))
+	}
+}
+
 func TestRecordFieldDefaultValue(t *testing.T) {
 	testSchemaValid(t, `{"type":"record","name":"r1","fields":[{"name":"f1","type":"int","default":13}]}`)
 	testSchemaValid(t, `{"type":"record","name":"r1","fields":[{"name":"f1","type":"string","default":"foo"}]}`)
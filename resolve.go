@@ -0,0 +1,341 @@
+package goavro
+
+import (
+	"fmt"
+)
+
+// nativeResolver converts a datum value, as decoded using the schema of the
+// writer, to the datum value that would have been decoded using the schema of
+// the reader.
+type nativeResolver func(datum interface{}) (interface{}, error)
+
+// resolverKey identifies the resolution of a writer codec to a reader codec.
+type resolverKey struct {
+	writer, reader *Codec
+}
+
+// nativeResolverBuilder resolves writer codecs to reader codecs, resolving
+// each combination of writer and reader only once, so recursive schemas may be
+// resolved.
+type nativeResolverBuilder struct {
+	resolved map[resolverKey]*nativeResolver
+	failed   map[resolverKey]error
+	wrap     func(string, interface{}) interface{} // wraps decoded union values of reader
+}
+
+// newNativeResolver returns a function that converts data items decoded using
+// writer to the data items that would have been decoded using reader,
+// following the Avro schema resolution rules, or an error when the schema of
+// writer cannot be resolved to the schema of reader.
+//
+// Named types resolve when their unqualified names are the same, or when the
+// full name of the writer type is an alias of the reader type. Record fields
+// are matched by name or by the aliases of the reader field. Writer fields
+// missing from the reader are dropped, and reader fields missing from the
+// writer are set to their default values. Values of a writer union are
+// resolved using the writer member that encoded them, and values are promoted
+// into the first member of a reader union to which they resolve, preferring a
+// member of the same type. The int, long, and float types are promoted to
+// wider numeric types, and string and bytes are promoted to one another.
+//
+// As in the Avro specification, a writer union member or enum symbol that
+// cannot be resolved is only an error when a datum value uses it.
+func newNativeResolver(writer, reader *Codec) (nativeResolver, error) {
+	b := &nativeResolverBuilder{
+		resolved: make(map[resolverKey]*nativeResolver),
+		failed:   make(map[resolverKey]error),
+		wrap:     unionWrapper(reader.config.UnionDecoding),
+	}
+	return b.resolve(writer, reader)
+}
+
+func (b *nativeResolverBuilder) resolve(writer, reader *Codec) (nativeResolver, error) {
+	key := resolverKey{writer, reader}
+	if err, ok := b.failed[key]; ok {
+		return nil, err
+	}
+	if p, ok := b.resolved[key]; ok {
+		// NOTE: When resolving a recursive schema, the resolver may not yet be
+		// built, so defer dereferencing it until it is used.
+		return func(datum interface{}) (interface{}, error) { return (*p)(datum) }, nil
+	}
+	p := new(nativeResolver)
+	b.resolved[key] = p
+	r, err := b.build(writer, reader)
+	if err != nil {
+		b.failed[key] = err
+		r = func(interface{}) (interface{}, error) { return nil, err }
+	}
+	*p = r
+	return r, err
+}
+
+func (b *nativeResolverBuilder) build(writer, reader *Codec) (nativeResolver, error) {
+	wt, rt := writer.avroType(), reader.avroType()
+	switch {
+	case wt == "union":
+		return b.resolveWriterUnion(writer, reader)
+	case rt == "union":
+		return b.resolveReaderUnion(writer, reader)
+	}
+
+	if wt != rt {
+		if promote := promotion(wt, rt); promote != nil {
+			return promote, nil
+		}
+		return nil, fmt.Errorf("cannot resolve %s to %s", describeBinding(writer), describeBinding(reader))
+	}
+
+	switch wt {
+	case "array", "map":
+		return b.resolveCollection(writer, reader)
+	case "enum", "fixed", "record":
+		if !namesMatch(writer, reader) {
+			return nil, fmt.Errorf("cannot resolve %s %q to %s %q: names do not match", wt, writer.typeName, rt, reader.typeName)
+		}
+	}
+
+	switch wt {
+	case "enum":
+		return resolveEnum(writer, reader), nil
+	case "fixed":
+		if writer.size != reader.size {
+			return nil, fmt.Errorf("cannot resolve fixed %q of size %d to fixed %q of size %d", writer.typeName, writer.size, reader.typeName, reader.size)
+		}
+	case "record":
+		return b.resolveRecord(writer, reader)
+	}
+	return func(datum interface{}) (interface{}, error) { return datum, nil }, nil
+}
+
+// namesMatch returns true when the named type of writer may be resolved to the
+// named type of reader.
+func namesMatch(writer, reader *Codec) bool {
+	if writer.typeName.short() == reader.typeName.short() {
+		return true
+	}
+	for _, alias := range reader.aliases {
+		if alias == writer.typeName.fullName {
+			return true
+		}
+	}
+	return false
+}
+
+// promotion returns the resolver promoting values of the writer primitive type
+// to the reader primitive type, or nil when there is no such promotion.
+func promotion(writerType, readerType string) nativeResolver {
+	switch writerType + ">" + readerType {
+	case "int>long":
+		return func(datum interface{}) (interface{}, error) { return int64(datum.(int32)), nil }
+	case "int>float":
+		return func(datum interface{}) (interface{}, error) { return float32(datum.(int32)), nil }
+	case "int>double":
+		return func(datum interface{}) (interface{}, error) { return float64(datum.(int32)), nil }
+	case "long>float":
+		return func(datum interface{}) (interface{}, error) { return float32(datum.(int64)), nil }
+	case "long>double":
+		return func(datum interface{}) (interface{}, error) { return float64(datum.(int64)), nil }
+	case "float>double":
+		return func(datum interface{}) (interface{}, error) { return float64(datum.(float32)), nil }
+	case "string>bytes":
+		return func(datum interface{}) (interface{}, error) { return []byte(datum.(string)), nil }
+	case "bytes>string":
+		return func(datum interface{}) (interface{}, error) { return string(datum.([]byte)), nil }
+	}
+	return nil
+}
+
+func (b *nativeResolverBuilder) resolveCollection(writer, reader *Codec) (nativeResolver, error) {
+	resolveItem, err := b.resolve(writer.items, reader.items)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %s", writer.avroType(), err)
+	}
+	if writer.avroType() == "map" {
+		return func(datum interface{}) (interface{}, error) {
+			values := datum.(map[string]interface{})
+			resolved := make(map[string]interface{}, len(values))
+			for key, value := range values {
+				v, err := resolveItem(value)
+				if err != nil {
+					return nil, fmt.Errorf("cannot resolve map value %q: %s", key, err)
+				}
+				resolved[key] = v
+			}
+			return resolved, nil
+		}, nil
+	}
+	return func(datum interface{}) (interface{}, error) {
+		items := datum.([]interface{})
+		resolved := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := resolveItem(item)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve array item %d: %s", i+1, err)
+			}
+			resolved[i] = v
+		}
+		return resolved, nil
+	}, nil
+}
+
+func resolveEnum(writer, reader *Codec) nativeResolver {
+	symbols := make(map[string]struct{}, len(reader.symbols))
+	for _, symbol := range reader.symbols {
+		symbols[symbol] = struct{}{}
+	}
+	return func(datum interface{}) (interface{}, error) {
+		if _, ok := symbols[datum.(string)]; !ok {
+			return nil, fmt.Errorf("cannot resolve enum %q symbol %q: not a symbol of enum %q", writer.typeName, datum, reader.typeName)
+		}
+		return datum, nil
+	}
+}
+
+// resolvedField describes how a reader record field is set from the fields of
+// a writer record.
+type resolvedField struct {
+	name         string         // name of reader field
+	writerName   string         // name of writer field, when resolve is not nil
+	resolve      nativeResolver // resolves value of writer field
+	codec        *Codec         // codec of reader field, used to decode defaultValue
+	defaultValue []byte         // binary encoded default value, when resolve is nil
+}
+
+func (b *nativeResolverBuilder) resolveRecord(writer, reader *Codec) (nativeResolver, error) {
+	writerFields := make(map[string]*recordField, len(writer.fields))
+	for i := range writer.fields {
+		writerFields[writer.fields[i].name] = &writer.fields[i]
+	}
+
+	fields := make([]resolvedField, len(reader.fields))
+	for i, rf := range reader.fields {
+		field := resolvedField{name: rf.name, codec: rf.codec}
+
+		wf, ok := writerFields[rf.name]
+		for j := 0; !ok && j < len(rf.aliases); j++ {
+			wf, ok = writerFields[rf.aliases[j]]
+		}
+		if ok {
+			resolve, err := b.resolve(wf.codec, rf.codec)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve record %q field %q: %s", reader.typeName, rf.name, err)
+			}
+			field.writerName, field.resolve = wf.name, resolve
+		} else {
+			if !rf.hasDefault {
+				return nil, fmt.Errorf("cannot resolve record %q field %q: no writer field and schema does not specify default value", reader.typeName, rf.name)
+			}
+			// NOTE: Decode the default value for each datum value, so each
+			// record has its own copy of it.
+			var err error
+			if field.defaultValue, err = rf.codec.binaryFromNative(nil, rf.defaultValue); err != nil {
+				return nil, fmt.Errorf("cannot resolve record %q field %q: %s", reader.typeName, rf.name, err)
+			}
+		}
+		fields[i] = field
+	}
+
+	return func(datum interface{}) (interface{}, error) {
+		values := datum.(map[string]interface{})
+		resolved := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			var value interface{}
+			var err error
+			if field.resolve != nil {
+				value, err = field.resolve(values[field.writerName])
+			} else {
+				value, _, err = field.codec.nativeFromBinary(field.defaultValue)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve record %q field %q: %s", reader.typeName, field.name, err)
+			}
+			resolved[field.name] = value
+		}
+		return resolved, nil
+	}, nil
+}
+
+func (b *nativeResolverBuilder) resolveWriterUnion(writer, reader *Codec) (nativeResolver, error) {
+	resolvers := make(map[string]nativeResolver, len(writer.members))
+	failures := make(map[string]error)
+	var resolvable bool
+	for _, member := range writer.members {
+		branch := member.typeName.fullName
+		resolve, err := b.resolve(member, reader)
+		if err != nil {
+			failures[branch] = err
+			continue
+		}
+		resolvers[branch] = resolve
+		resolvable = true
+	}
+	if !resolvable {
+		return nil, fmt.Errorf("cannot resolve any member of union to %s", describeBinding(reader))
+	}
+
+	return func(datum interface{}) (interface{}, error) {
+		branch, value := "null", datum
+		switch v := unionDatum(datum).(type) {
+		case nil:
+			// null branch
+		case map[string]interface{}:
+			if len(v) != 1 {
+				return nil, fmt.Errorf("cannot resolve union: expected map with single key; received: %v", v)
+			}
+			for k, vv := range v {
+				branch, value = k, vv // will execute exactly once
+			}
+		default:
+			return nil, fmt.Errorf("cannot resolve union: expected nil or map[string]interface{}; received: %T", datum)
+		}
+		resolve, ok := resolvers[branch]
+		if !ok {
+			if err, ok := failures[branch]; ok {
+				return nil, fmt.Errorf("cannot resolve union member %q: %s", branch, err)
+			}
+			return nil, fmt.Errorf("cannot resolve union member %q: not a member of writer union", branch)
+		}
+		return resolve(value)
+	}, nil
+}
+
+func (b *nativeResolverBuilder) resolveReaderUnion(writer, reader *Codec) (nativeResolver, error) {
+	// NOTE: Prefer the first member of the same type, before the first member
+	// to which the writer type may be promoted.
+	wt := writer.avroType()
+	member := -1
+	for i, m := range reader.members {
+		if m.avroType() == wt && (m.typeName.fullName == wt || namesMatch(writer, m)) {
+			member = i
+			break
+		}
+	}
+	var resolve nativeResolver
+	var err error
+	if member >= 0 {
+		if resolve, err = b.resolve(writer, reader.members[member]); err != nil {
+			return nil, err
+		}
+	} else {
+		for i, m := range reader.members {
+			if resolve, err = b.resolve(writer, m); err == nil {
+				member = i
+				break
+			}
+		}
+		if member < 0 {
+			return nil, fmt.Errorf("cannot resolve %s to any member of union", describeBinding(writer))
+		}
+	}
+
+	branch := reader.members[member].typeName.fullName
+	return func(datum interface{}) (interface{}, error) {
+		value, err := resolve(datum)
+		if err != nil || value == nil {
+			// do not wrap a nil value, just as union decoder does not
+			return value, err
+		}
+		return b.wrap(branch, value), nil
+	}, nil
+}
@@ -0,0 +1,74 @@
+package goavro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Streams are a lightweight alternative to OCF for logs and sockets, in which
+// each binary encoded datum is prefixed by its length in bytes, so it may be
+// read without decoding the data items preceding it. Unlike OCF, a stream has
+// no header, so the reader must be provided the schema used by the writer.
+
+const (
+	// StreamFramingLongLabel is used when each datum of a stream is prefixed
+	// by its length in bytes, encoded as an Avro long, which is a zig-zag
+	// encoded variable length integer.
+	StreamFramingLongLabel = "long"
+
+	// StreamFramingUint32Label is used when each datum of a stream is
+	// prefixed by its length in bytes, encoded as a 4 byte big-endian
+	// unsigned integer.
+	StreamFramingUint32Label = "uint32"
+)
+
+// maxStreamFramePrefixLength is the maximum number of bytes of the length
+// prefix of a frame, which is the length of the longest encoded long.
+const maxStreamFramePrefixLength = 10
+
+// newStreamCodec returns codec when not nil, otherwise a new Codec created
+// from schema.
+func newStreamCodec(codec *Codec, schema string) (*Codec, error) {
+	if codec != nil {
+		return codec, nil
+	}
+	if schema == "" {
+		return nil, errors.New("without either Codec or Schema specified")
+	}
+	return NewCodec(schema)
+}
+
+// streamFraming validates the framing and maximum frame size of a stream,
+// returning them with their default values applied.
+func streamFraming(framing string, maxFrameSize int64) (string, int64, error) {
+	switch framing {
+	case "":
+		framing = StreamFramingLongLabel
+	case StreamFramingLongLabel, StreamFramingUint32Label:
+		// no-op
+	default:
+		return "", 0, fmt.Errorf("using unrecognized framing: %q", framing)
+	}
+	if maxFrameSize < 0 {
+		return "", 0, fmt.Errorf("when MaxFrameSize is negative: %d", maxFrameSize)
+	}
+	if maxFrameSize == 0 {
+		maxFrameSize = MaxBlockSize
+	}
+	if framing == StreamFramingUint32Label && maxFrameSize > math.MaxUint32 {
+		maxFrameSize = math.MaxUint32
+	}
+	return framing, maxFrameSize, nil
+}
+
+// appendStreamFramePrefix appends the length prefix of a frame of size bytes.
+func appendStreamFramePrefix(buf []byte, framing string, size int) []byte {
+	if framing == StreamFramingUint32Label {
+		buf = append(buf, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(size))
+		return buf
+	}
+	return longToBinary(buf, int64(size))
+}
@@ -0,0 +1,256 @@
+package goavro
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// StreamReaderConfig is used to specify creation parameters for StreamReader.
+type StreamReaderConfig struct {
+	// Codec specifies the Codec used to decode data items, (optional). If
+	// omitted, a new Codec is created from Schema.
+	Codec *Codec
+
+	// Schema specifies the Avro schema of the data items, (optional), used
+	// only when Codec is omitted.
+	Schema string
+
+	// Framing specifies how the length of each datum is encoded, (optional).
+	// Either StreamFramingLongLabel or StreamFramingUint32Label. If omitted,
+	// defaults to StreamFramingLongLabel.
+	Framing string
+
+	// MaxFrameSize specifies the maximum number of bytes of an encoded datum,
+	// (optional). If omitted, or 0, defaults to MaxBlockSize. This check is
+	// to ensure a corrupt length prefix will not cause the library to over
+	// allocate RAM.
+	MaxFrameSize int64
+
+	// BufferSize specifies the size of the buffer used to read from the
+	// underlying io.Reader, (optional). If omitted, or 0, defaults to
+	// DefaultBlockSize.
+	BufferSize int
+
+	// Offset specifies the position in the stream from which to resume
+	// reading, as previously returned by the Offset method, (optional). Offset
+	// is relative to the current position of the io.Reader, which ought to be
+	// the position at which the earlier StreamReader started, such as the
+	// start of a newly opened file. When the io.Reader is an io.Seeker, it is
+	// moved Offset bytes forward from its current position, and otherwise,
+	// including when seeking fails, such as for a pipe, Offset bytes are read
+	// and discarded. Offsets returned by the Offset method include Offset.
+	Offset int64
+}
+
+// StreamReader reads a stream of binary encoded data items, each prefixed by
+// its length in bytes, as written by StreamWriter.
+type StreamReader struct {
+	br           *bufio.Reader
+	codec        *Codec
+	framing      string
+	maxFrameSize int64
+	err          error // most recent error that took place while reading bytes (unrecoverable)
+	readReady    bool  // true after Scan and before Read
+
+	frame       []byte // encoded datum of most recently scanned frame, reused for each frame
+	frameOffset int64  // offset of most recently scanned frame
+	offset      int64  // offset of first frame whose datum has not been read
+	next        int64  // offset of frame following most recently scanned frame
+}
+
+// NewStreamReader returns a new StreamReader, which reads data items from ior,
+// positioned at the start of the stream.
+//
+//     func example(ior io.Reader) error {
+//         sr, err := goavro.NewStreamReader(ior, goavro.StreamReaderConfig{
+//             Schema: `"string"`,
+//         })
+//         if err != nil {
+//             return err
+//         }
+//         for sr.Scan() {
+//             datum, err := sr.Read()
+//             if err != nil {
+//                 return err
+//             }
+//             fmt.Println(datum)
+//         }
+//         return sr.Err()
+//     }
+func NewStreamReader(ior io.Reader, config StreamReaderConfig) (*StreamReader, error) {
+	codec, err := newStreamCodec(config.Codec, config.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create StreamReader %s", err)
+	}
+	framing, maxFrameSize, err := streamFraming(config.Framing, config.MaxFrameSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create StreamReader %s", err)
+	}
+	if config.BufferSize < 0 {
+		return nil, fmt.Errorf("cannot create StreamReader when BufferSize is negative: %d", config.BufferSize)
+	}
+	if config.BufferSize == 0 {
+		config.BufferSize = DefaultBlockSize
+	}
+	if config.Offset < 0 {
+		return nil, fmt.Errorf("cannot create StreamReader when Offset is negative: %d", config.Offset)
+	}
+	if config.Offset > 0 {
+		if err = skipBytes(ior, config.Offset); err != nil {
+			return nil, fmt.Errorf("cannot create StreamReader at Offset %d: %s", config.Offset, err)
+		}
+	}
+	return &StreamReader{
+		br:           bufio.NewReaderSize(ior, config.BufferSize),
+		codec:        codec,
+		framing:      framing,
+		maxFrameSize: maxFrameSize,
+		frameOffset:  config.Offset,
+		offset:       config.Offset,
+		next:         config.Offset,
+	}, nil
+}
+
+// skipBytes moves ior count bytes forward from its current position, by
+// seeking when possible, and otherwise by reading and discarding them.
+func skipBytes(ior io.Reader, count int64) error {
+	// NOTE: Some io.Seekers, such as an *os.File for a pipe or standard input,
+	// cannot seek, in which case the bytes are read instead.
+	if seeker, ok := ior.(io.Seeker); ok {
+		if _, err := seeker.Seek(count, io.SeekCurrent); err == nil {
+			return nil
+		}
+	}
+	_, err := io.CopyN(ioutil.Discard, ior, count)
+	return err
+}
+
+// Codec returns the codec used by StreamReader.
+func (sr *StreamReader) Codec() *Codec {
+	return sr.codec
+}
+
+// Err returns the last error encountered while reading the stream.
+func (sr *StreamReader) Err() error {
+	return sr.err
+}
+
+// Offset returns the position in the stream of the first frame whose datum
+// has not been read, relative to the position of the io.Reader when the first
+// StreamReader of the stream was created, which advances past each frame once Read is called for
+// it, or once Scan is called again without calling Read. It never advances
+// past an incomplete frame at the end of the stream, so once the data items
+// read so far have been processed, Offset may be saved, and used to resume
+// reading the stream later, after more frames have been written to it.
+func (sr *StreamReader) Offset() int64 {
+	return sr.offset
+}
+
+// Scan returns true when there is at least one more data item to be read from
+// the stream, having read its frame. Scan ought to be called prior to calling
+// the Read method each time the Read method is invoked.
+func (sr *StreamReader) Scan() bool {
+	sr.readReady = false
+	sr.offset = sr.next // skip frame of any datum not read
+
+	if sr.err != nil {
+		return false
+	}
+
+	size, n, err := sr.readFramePrefix()
+	if err != nil {
+		if err != io.EOF {
+			sr.err = fmt.Errorf("cannot read frame size at offset %d: %s", sr.offset, err)
+		}
+		return false
+	}
+	if size < 0 {
+		sr.err = fmt.Errorf("cannot read frame at offset %d when frame size is negative: %d", sr.offset, size)
+		return false
+	}
+	if size > sr.maxFrameSize {
+		sr.err = fmt.Errorf("cannot read frame at offset %d when frame size exceeds MaxFrameSize: %d > %d", sr.offset, size, sr.maxFrameSize)
+		return false
+	}
+
+	if int64(cap(sr.frame)) < size {
+		sr.frame = make([]byte, size)
+	}
+	sr.frame = sr.frame[:size]
+	if _, err = io.ReadFull(sr.br, sr.frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		sr.err = fmt.Errorf("cannot read frame at offset %d: %s", sr.offset, err)
+		return false
+	}
+
+	sr.frameOffset = sr.offset
+	sr.next = sr.offset + int64(n) + size
+	sr.readReady = true
+	return true
+}
+
+// readFramePrefix reads the length prefix of the next frame, returning the
+// frame size and the number of bytes of the prefix. It returns io.EOF only
+// when there are no more frames.
+func (sr *StreamReader) readFramePrefix() (int64, int, error) {
+	if sr.framing == StreamFramingUint32Label {
+		var prefix [4]byte
+		n, err := io.ReadFull(sr.br, prefix[:])
+		if err != nil {
+			return 0, n, err // io.EOF only when no bytes read
+		}
+		return int64(binary.BigEndian.Uint32(prefix[:])), n, nil
+	}
+
+	var value uint64
+	var shift uint
+	for n := 1; n <= maxStreamFramePrefixLength; n++ {
+		b, err := sr.br.ReadByte()
+		if err != nil {
+			if err == io.EOF && n > 1 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, n - 1, err
+		}
+		value |= uint64(b&intMask) << shift
+		if b&intFlag == 0 {
+			return int64(value>>1) ^ -int64(value&1), n, nil
+		}
+		shift += 7
+	}
+	return 0, maxStreamFramePrefixLength, errors.New("frame size is longer than a long")
+}
+
+// Read consumes one datum value from the stream and returns it. Read is
+// designed to be called only once after each invocation of the Scan method.
+// Because each datum is framed, a datum which cannot be decoded does not
+// prevent reading subsequent data items, so the error is returned without
+// also being returned by Err.
+func (sr *StreamReader) Read() (interface{}, error) {
+	// NOTE: Test previous error before testing readReady to prevent overwriting
+	// previous error.
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	if !sr.readReady {
+		sr.err = errors.New("Read called without successful Scan")
+		return nil, sr.err
+	}
+	sr.readReady = false
+	sr.offset = sr.next
+
+	datum, buf, err := sr.codec.NativeFromBinary(sr.frame)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode datum at offset %d: %s", sr.frameOffset, err)
+	}
+	if len(buf) != 0 {
+		return nil, fmt.Errorf("cannot decode datum at offset %d: extra bytes in frame after datum: %d", sr.frameOffset, len(buf))
+	}
+	return datum, nil
+}
@@ -0,0 +1,290 @@
+package goavro_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/karrick/goavro"
+)
+
+func newStreamOfStrings(t *testing.T, framing string, data ...string) []byte {
+	t.Helper()
+	bb := new(bytes.Buffer)
+	sw, err := goavro.NewStreamWriter(goavro.StreamWriterConfig{W: bb, Schema: `"string"`, Framing: framing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, datum := range data {
+		if err = sw.Write(datum); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bb.Bytes()
+}
+
+func readStreamOfStrings(t *testing.T, sr *goavro.StreamReader) []string {
+	t.Helper()
+	var data []string
+	for sr.Scan() {
+		datum, err := sr.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, datum.(string))
+	}
+	return data
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	data := []string{"", "hello", string(make([]byte, 300))}
+
+	for _, framing := range []string{"", goavro.StreamFramingLongLabel, goavro.StreamFramingUint32Label} {
+		buf := newStreamOfStrings(t, framing, data...)
+
+		sr, err := goavro.NewStreamReader(bytes.NewReader(buf), goavro.StreamReaderConfig{Schema: `"string"`, Framing: framing, BufferSize: 16})
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := readStreamOfStrings(t, sr)
+		if err = sr.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(actual) != len(data) {
+			t.Fatalf("Framing: %q; Actual: %v; Expected: %v", framing, len(actual), len(data))
+		}
+		for i := range data {
+			if actual[i] != data[i] {
+				t.Errorf("Framing: %q; Actual: %q; Expected: %q", framing, actual[i], data[i])
+			}
+		}
+		if actual, expected := sr.Offset(), int64(len(buf)); actual != expected {
+			t.Errorf("Framing: %q; Actual: %v; Expected: %v", framing, actual, expected)
+		}
+	}
+}
+
+func TestStreamFraming(t *testing.T) {
+	// "hi" is encoded as length 2 followed by the bytes, which is then framed.
+	if actual, expected := newStreamOfStrings(t, goavro.StreamFramingLongLabel, "hi"), []byte{6, 4, 'h', 'i'}; !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := newStreamOfStrings(t, goavro.StreamFramingUint32Label, "hi"), []byte{0, 0, 0, 3, 4, 'h', 'i'}; !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestStreamResumeFromOffset(t *testing.T) {
+	for _, framing := range []string{goavro.StreamFramingLongLabel, goavro.StreamFramingUint32Label} {
+		buf := newStreamOfStrings(t, framing, "one", "two", "three")
+
+		// Stream ends part way through the final frame.
+		torn := buf[:len(buf)-2]
+		sr, err := goavro.NewStreamReader(bytes.NewReader(torn), goavro.StreamReaderConfig{Schema: `"string"`, Framing: framing})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, expected := len(readStreamOfStrings(t, sr)), 2; actual != expected {
+			t.Errorf("Framing: %q; Actual: %v; Expected: %v", framing, actual, expected)
+		}
+		ensureError(t, sr.Err(), "cannot read frame", "unexpected EOF")
+		offset := sr.Offset()
+		if actual, expected := offset, int64(len(newStreamOfStrings(t, framing, "one", "two"))); actual != expected {
+			t.Errorf("Framing: %q; Actual: %v; Expected: %v", framing, actual, expected)
+		}
+
+		// Resume once the final frame is complete, both with and without
+		// seeking, including from an io.Seeker which cannot seek.
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			_, _ = pw.Write(buf)
+			_ = pw.Close()
+		}()
+		for _, ior := range []io.Reader{bytes.NewReader(buf), bytes.NewBuffer(buf), pr} {
+			sr, err = goavro.NewStreamReader(ior, goavro.StreamReaderConfig{Schema: `"string"`, Framing: framing, Offset: offset})
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := readStreamOfStrings(t, sr)
+			if err = sr.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != 1 || data[0] != "three" {
+				t.Errorf("Framing: %q; Actual: %v; Expected: %v", framing, data, []string{"three"})
+			}
+		}
+		_ = pr.Close()
+	}
+}
+
+func TestStreamReaderOffsetRelative(t *testing.T) {
+	prefix := []byte("not part of stream")
+	stream := newStreamOfStrings(t, "", "one", "two")
+	buf := append(append([]byte(nil), prefix...), stream...)
+
+	// offset is relative to position of io.Reader when reading started
+	br := bytes.NewReader(buf)
+	if _, err := br.Seek(int64(len(prefix)), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	sr, err := goavro.NewStreamReader(br, goavro.StreamReaderConfig{Schema: `"string"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sr.Scan() {
+		t.Fatal(sr.Err())
+	}
+	if _, err = sr.Read(); err != nil {
+		t.Fatal(err)
+	}
+	offset := sr.Offset()
+
+	br = bytes.NewReader(buf)
+	if _, err = br.Seek(int64(len(prefix)), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	sr, err = goavro.NewStreamReader(br, goavro.StreamReaderConfig{Schema: `"string"`, Offset: offset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := readStreamOfStrings(t, sr), []string{"two"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := sr.Offset(), int64(len(stream)); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestStreamReaderOffsetBeforeRead(t *testing.T) {
+	buf := newStreamOfStrings(t, "", "one", "two")
+
+	sr, err := goavro.NewStreamReader(bytes.NewReader(buf), goavro.StreamReaderConfig{Schema: `"string"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sr.Scan() {
+		t.Fatal(sr.Err())
+	}
+	if actual, expected := sr.Offset(), int64(0); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if _, err = sr.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := sr.Offset(), int64(5); actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestStreamReaderDecodeError(t *testing.T) {
+	buf := newStreamOfStrings(t, "", "one")
+	buf = append(buf, 4, 1, 2) // frame of long, followed by extra byte
+	buf = append(buf, newStreamOfStrings(t, "", "two")...)
+
+	sr, err := goavro.NewStreamReader(bytes.NewReader(buf), goavro.StreamReaderConfig{Schema: `"long"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values, errs int
+	for sr.Scan() {
+		if _, err = sr.Read(); err != nil {
+			ensureError(t, err, "cannot decode datum at offset")
+			errs++
+			continue
+		}
+		values++
+	}
+	if err = sr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// Strings "one" and "two" have odd lengths, which decode as negative
+	// longs without consuming the entire frame.
+	if actual, expected := errs, 3; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	if actual, expected := values, 0; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestStreamMaxFrameSize(t *testing.T) {
+	bb := new(bytes.Buffer)
+	sw, err := goavro.NewStreamWriter(goavro.StreamWriterConfig{W: bb, Schema: `"string"`, MaxFrameSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, sw.Write("this is too long"), "exceeds MaxFrameSize")
+	if err = sw.Write("short"); err != nil {
+		t.Fatal(err) // frame size error is not sticky
+	}
+
+	buf := newStreamOfStrings(t, "", "short", "this is too long", "short")
+	sr, err := goavro.NewStreamReader(bytes.NewReader(buf), goavro.StreamReaderConfig{Schema: `"string"`, MaxFrameSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := len(readStreamOfStrings(t, sr)), 1; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+	ensureError(t, sr.Err(), "exceeds MaxFrameSize: 17 > 8")
+
+	sr, err = goavro.NewStreamReader(bytes.NewReader([]byte{1}), goavro.StreamReaderConfig{Schema: `"string"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr.Scan() {
+		t.Errorf("Actual: %v; Expected: %v", true, false)
+	}
+	ensureError(t, sr.Err(), "frame size is negative")
+}
+
+func TestStreamWriterErrors(t *testing.T) {
+	fw := &failingWriter{remaining: 1}
+	sw, err := goavro.NewStreamWriter(goavro.StreamWriterConfig{W: fw, Schema: `"string"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, sw.Write(13), "cannot translate datum to binary")
+	if err = sw.Write("one"); err != nil {
+		t.Fatal(err)
+	}
+	ensureError(t, sw.Write("two"), "failing writer")
+
+	// Once writing bytes fails, the error is returned without writing more.
+	fw.remaining = 1
+	ensureError(t, sw.Write("three"), "failing writer")
+	if actual, expected := fw.remaining, 1; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestStreamConfigErrors(t *testing.T) {
+	_, err := goavro.NewStreamWriter(goavro.StreamWriterConfig{Schema: `"string"`})
+	ensureError(t, err, "cannot create StreamWriter when W is nil")
+
+	_, err = goavro.NewStreamWriter(goavro.StreamWriterConfig{W: ioutil.Discard})
+	ensureError(t, err, "cannot create StreamWriter without either Codec or Schema specified")
+
+	_, err = goavro.NewStreamWriter(goavro.StreamWriterConfig{W: ioutil.Discard, Schema: `"string"`, Framing: "varint"})
+	ensureError(t, err, "cannot create StreamWriter using unrecognized framing")
+
+	_, err = goavro.NewStreamReader(nil, goavro.StreamReaderConfig{Schema: `"string"`, MaxFrameSize: -1})
+	ensureError(t, err, "cannot create StreamReader when MaxFrameSize is negative")
+
+	_, err = goavro.NewStreamReader(bytes.NewBuffer([]byte{1, 2}), goavro.StreamReaderConfig{Schema: `"string"`, Offset: 3})
+	ensureError(t, err, "cannot create StreamReader at Offset 3", "EOF")
+
+	sr, err := goavro.NewStreamReader(bytes.NewReader(nil), goavro.StreamReaderConfig{Schema: `"string"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sr.Read()
+	ensureError(t, err, "Read called without successful Scan")
+}
@@ -0,0 +1,103 @@
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamWriterConfig is used to specify creation parameters for StreamWriter.
+type StreamWriterConfig struct {
+	// W specifies the `io.Writer` to which to send the framed data items,
+	// (required).
+	W io.Writer
+
+	// Codec specifies the Codec used to encode data items, (optional). If
+	// omitted, a new Codec is created from Schema.
+	Codec *Codec
+
+	// Schema specifies the Avro schema of the data items, (optional), used
+	// only when Codec is omitted.
+	Schema string
+
+	// Framing specifies how the length of each datum is encoded, (optional).
+	// Either StreamFramingLongLabel or StreamFramingUint32Label. If omitted,
+	// defaults to StreamFramingLongLabel.
+	Framing string
+
+	// MaxFrameSize specifies the maximum number of bytes of an encoded datum,
+	// (optional). If omitted, or 0, defaults to MaxBlockSize. Write returns
+	// an error rather than writing a larger datum.
+	MaxFrameSize int64
+}
+
+// StreamWriter writes a stream of binary encoded data items, each prefixed by
+// its length in bytes.
+type StreamWriter struct {
+	iow          io.Writer
+	codec        *Codec
+	framing      string
+	maxFrameSize int64
+	err          error // most recent error that took place while writing bytes (unrecoverable)
+
+	// Working buffers reused for each datum.
+	datum []byte // encoded datum
+	frame []byte // length prefix and encoded datum
+}
+
+// NewStreamWriter returns a new StreamWriter, which writes data items to W
+// without any header.
+//
+//     func example(conn net.Conn) error {
+//         sw, err := goavro.NewStreamWriter(goavro.StreamWriterConfig{
+//             W:      conn,
+//             Schema: `"string"`,
+//         })
+//         if err != nil {
+//             return err
+//         }
+//         return sw.Write("hello")
+//     }
+func NewStreamWriter(config StreamWriterConfig) (*StreamWriter, error) {
+	if config.W == nil {
+		return nil, errors.New("cannot create StreamWriter when W is nil")
+	}
+	codec, err := newStreamCodec(config.Codec, config.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create StreamWriter %s", err)
+	}
+	framing, maxFrameSize, err := streamFraming(config.Framing, config.MaxFrameSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create StreamWriter %s", err)
+	}
+	return &StreamWriter{iow: config.W, codec: codec, framing: framing, maxFrameSize: maxFrameSize}, nil
+}
+
+// Codec returns the codec used by StreamWriter.
+func (sw *StreamWriter) Codec() *Codec {
+	return sw.codec
+}
+
+// Write encodes a single data item, and writes it along with its length
+// prefix to the underlying io.Writer using a single call to its Write method.
+// Once an error writing to the underlying io.Writer has occurred, it is
+// returned by all subsequent calls.
+func (sw *StreamWriter) Write(datum interface{}) error {
+	if sw.err != nil {
+		return sw.err
+	}
+	encoded, err := sw.codec.BinaryFromNative(sw.datum[:0], datum)
+	if err != nil {
+		return fmt.Errorf("cannot translate datum to binary: %v; %s", datum, err)
+	}
+	sw.datum = encoded
+	if size := int64(len(encoded)); size > sw.maxFrameSize {
+		return fmt.Errorf("cannot write frame when frame size exceeds MaxFrameSize: %d > %d", size, sw.maxFrameSize)
+	}
+
+	sw.frame = append(appendStreamFramePrefix(sw.frame[:0], sw.framing, len(encoded)), encoded...)
+	if _, err = sw.iow.Write(sw.frame); err != nil {
+		sw.err = err
+	}
+	return err
+}
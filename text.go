@@ -30,3 +30,18 @@ func advanceToNonWhitespace(buf []byte) ([]byte, error) {
 	}
 	return nil, io.ErrShortBuffer
 }
+
+// isJSONValueBoundary returns true when the remaining bytes, after skipping any
+// whitespace, are empty or begin with a byte that terminates a JSON value
+// nested inside an array or object.
+func isJSONValueBoundary(buf []byte) bool {
+	buf, err := advanceToNonWhitespace(buf)
+	if err != nil {
+		return true // nothing but whitespace remains
+	}
+	switch buf[0] {
+	case ',', ']', '}':
+		return true
+	}
+	return false
+}
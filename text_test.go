@@ -187,3 +187,46 @@ func testTextCodecPass(t *testing.T, schema string, datum interface{}, buf []byt
 	testTextDecodePass(t, schema, datum, buf)
 	testTextEncodePass(t, schema, datum, buf)
 }
+
+// testJSONCodecPass does a bi-directional standard JSON codec check, by
+// encoding datum to bytes, then decoding bytes back to datum.
+func testJSONCodecPass(t *testing.T, schema string, config goavro.CodecConfig, datum interface{}, buf []byte) {
+	codec, err := goavro.NewCodecWithConfig(schema, config)
+	if err != nil {
+		t.Fatalf("schema: %s; %s", schema, err)
+	}
+
+	encoded, err := codec.JSONFromNative(nil, datum)
+	if err != nil {
+		t.Fatalf("schema: %s; Datum: %v; %s", schema, datum, err)
+	}
+	if !bytes.Equal(encoded, buf) {
+		t.Errorf("schema: %s; Datum: %v; Actual: %+q; Expected: %+q", schema, datum, encoded, buf)
+	}
+
+	decoded, remaining, err := codec.NativeFromJSON(buf)
+	if err != nil {
+		t.Fatalf("schema: %s; %s", schema, err)
+	}
+	if actual, expected := len(remaining), 0; actual != expected {
+		t.Errorf("schema: %s; Datum: %v; Actual: %v; Expected: %v", schema, datum, actual, expected)
+	}
+	if actual, expected := fmt.Sprintf("%v", decoded), fmt.Sprintf("%v", datum); actual != expected {
+		t.Errorf("schema: %s; Actual: %v; Expected: %v", schema, actual, expected)
+	}
+}
+
+func testJSONDecodeFail(t *testing.T, schema string, buf []byte, errorMessage string) {
+	c, err := goavro.NewCodec(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, newBuffer, err := c.NativeFromJSON(buf)
+	ensureError(t, err, errorMessage)
+	if value != nil {
+		t.Errorf("Actual: %v; Expected: %v", value, nil)
+	}
+	if !bytes.Equal(buf, newBuffer) {
+		t.Errorf("Actual: %v; Expected: %v", newBuffer, buf)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"reflect"
 )
 
 // Union wraps a datum value in a map for encoding as a Union, as required by
@@ -34,7 +35,39 @@ func Union(name string, datum interface{}) interface{} {
 	return map[string]interface{}{name: datum}
 }
 
-func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace string, schemaArray []interface{}) (*Codec, error) {
+// UnionValue is a union datum value along with the full name of the union
+// member type that encodes it. It is returned by the decoders in place of
+// non-nil union values when the Codec is created with CodecConfig.UnionDecoding
+// set to UnionDecodingValueLabel. The encoders accept a UnionValue anywhere a
+// union datum value is expected.
+type UnionValue struct {
+	Branch string      // full name of the union member type
+	Value  interface{} // datum value
+}
+
+// unionDatum returns the datum value wrapped in a map as required by the union
+// encoders when datum is a UnionValue, otherwise datum unchanged.
+func unionDatum(datum interface{}) interface{} {
+	if v, ok := datum.(UnionValue); ok {
+		return Union(v.Branch, v.Value)
+	}
+	return datum
+}
+
+// unionWrapper returns the function used by union decoders to wrap non-nil
+// decoded values, as specified by unionDecoding.
+func unionWrapper(unionDecoding string) func(string, interface{}) interface{} {
+	switch unionDecoding {
+	case UnionDecodingBareLabel:
+		return func(_ string, datum interface{}) interface{} { return datum }
+	case UnionDecodingValueLabel:
+		return func(name string, datum interface{}) interface{} { return UnionValue{Branch: name, Value: datum} }
+	default:
+		return Union
+	}
+}
+
+func buildCodecForTypeDescribedBySlice(st map[string]*Codec, config *CodecConfig, enclosingNamespace string, schemaArray []interface{}) (*Codec, error) {
 	if len(schemaArray) == 0 {
 		return nil, errors.New("Union ought to have one or more members")
 	}
@@ -45,7 +78,7 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 	indexFromName := make(map[string]int, len(schemaArray))
 
 	for i, unionMemberSchema := range schemaArray {
-		unionMemberCodec, err := buildCodec(st, enclosingNamespace, unionMemberSchema)
+		unionMemberCodec, err := buildCodec(st, config, enclosingNamespace, unionMemberSchema)
 		if err != nil {
 			return nil, fmt.Errorf("Union item %d ought to be valid Avro type: %s", i+1, err)
 		}
@@ -59,12 +92,33 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 		indexFromName[fullName] = i
 	}
 
-	return &Codec{
+	if config.UnionShortNames {
+		// NOTE: Allow members to also be addressed by their short name, but
+		// only when no other member has the same short name, and the short
+		// name is not the full name of another member.
+		countFromShortName := make(map[string]int, len(schemaArray))
+		for _, fullName := range allowedTypes {
+			countFromShortName[codecFromName[fullName].typeName.short()]++
+		}
+		for i, fullName := range allowedTypes {
+			shortName := codecFromName[fullName].typeName.short()
+			if _, ok := indexFromName[shortName]; ok || countFromShortName[shortName] > 1 {
+				continue
+			}
+			codecFromName[shortName] = codecFromIndex[i]
+			indexFromName[shortName] = i
+		}
+	}
+
+	wrapDecoded := unionWrapper(config.UnionDecoding)
+
+	c := &Codec{
 		// NOTE: To support record field default values, union schema set to the
 		// type name of first member
 		schema: codecFromIndex[0].typeName.short(),
 
 		typeName: &name{"union", nullNamespace},
+		members:  codecFromIndex,
 		nativeFromBinary: func(buf []byte) (interface{}, []byte, error) {
 			var decoded interface{}
 			var err error
@@ -86,10 +140,12 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 				// do not wrap a nil value in a map
 				return nil, buf, nil
 			}
-			// Non-nil values are wrapped in a map with single key set to type name of value
-			return Union(allowedTypes[index], decoded), buf, nil
+			// Non-nil values are wrapped in a map with single key set to type
+			// name of value, unless configured otherwise
+			return wrapDecoded(allowedTypes[index], decoded), buf, nil
 		},
 		binaryFromNative: func(buf []byte, datum interface{}) ([]byte, error) {
+			datum = unionDatum(datum)
 			switch v := datum.(type) {
 			case nil:
 				index, ok := indexFromName["null"]
@@ -121,16 +177,25 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 				}
 			}
 
-			var datum interface{}
+			var datum map[string]interface{}
 			var err error
 			datum, buf, err = genericMapTextDecoder(buf, nil, codecFromName)
 			if err != nil {
 				return nil, nil, fmt.Errorf("cannot decode textual union: %s", err)
 			}
+			if len(datum) == 1 {
+				// will execute exactly once
+				for key, value := range datum {
+					// NOTE: Key may be short name of member type, but always
+					// return full name.
+					return wrapDecoded(allowedTypes[indexFromName[key]], value), buf, nil
+				}
+			}
 
 			return datum, buf, nil
 		},
 		textualFromNative: func(buf []byte, datum interface{}) ([]byte, error) {
+			datum = unionDatum(datum)
 			switch v := datum.(type) {
 			case nil:
 				_, ok := indexFromName["null"]
@@ -150,7 +215,7 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 					}
 					buf = append(buf, '{')
 					var err error
-					buf, err = stringTextualFromNative(buf, key)
+					buf, err = stringTextualFromNative(buf, allowedTypes[index])
 					if err != nil {
 						return nil, fmt.Errorf("cannot encode textual union: %s", err)
 					}
@@ -165,5 +230,228 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 			}
 			return nil, fmt.Errorf("cannot encode textual union: non-nil values ought to be specified with Go map[string]interface{}, with single key equal to type name, and value equal to datum value: %v; received: %T", allowedTypes, datum)
 		},
-	}, nil
+	}
+
+	if config.standardJSON {
+		c.nativeFromTextual = func(buf []byte) (interface{}, []byte, error) {
+			if len(buf) >= 4 && bytes.Equal(buf[:4], nullBytes) {
+				if _, ok := indexFromName["null"]; ok {
+					return nil, buf[4:], nil
+				}
+			}
+			// NOTE: Standard JSON does not identify which member of the union
+			// a value belongs to, so try each member in the order specified by
+			// the schema, and select the first one that decodes the entire JSON
+			// value.
+			for index, memberCodec := range codecFromIndex {
+				if allowedTypes[index] == "null" {
+					continue // already checked above
+				}
+				datum, newBuf, err := memberCodec.nativeFromTextual(buf)
+				if err != nil || !isJSONValueBoundary(newBuf) {
+					continue
+				}
+				return wrapDecoded(allowedTypes[index], datum), newBuf, nil
+			}
+			return nil, nil, fmt.Errorf("cannot decode JSON union: no member schema types support datum: allowed types: %v", allowedTypes)
+		}
+		c.textualFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+			datum = unionDatum(datum)
+			switch v := datum.(type) {
+			case nil:
+				_, ok := indexFromName["null"]
+				if !ok {
+					return nil, fmt.Errorf("cannot encode JSON union: no member schema types support datum: allowed types: %v; received: %T", allowedTypes, datum)
+				}
+				return append(buf, nullBytes...), nil
+			case map[string]interface{}:
+				if len(v) != 1 {
+					return nil, fmt.Errorf("cannot encode JSON union: non-nil Union values ought to be specified with Go map[string]interface{}, with single key equal to type name, and value equal to datum value: %v; received: %T", allowedTypes, datum)
+				}
+				// will execute exactly once
+				for key, value := range v {
+					index, ok := indexFromName[key]
+					if !ok {
+						return nil, fmt.Errorf("cannot encode JSON union: no member schema types support datum: allowed types: %v; received: %T", allowedTypes, datum)
+					}
+					// NOTE: Standard JSON emits the value without wrapping it
+					// in an object keyed by its type name.
+					var err error
+					buf, err = codecFromIndex[index].textualFromNative(buf, value)
+					if err != nil {
+						return nil, fmt.Errorf("cannot encode JSON union: %s", err)
+					}
+					return buf, nil
+				}
+			}
+			return nil, fmt.Errorf("cannot encode JSON union: non-nil values ought to be specified with Go map[string]interface{}, with single key equal to type name, and value equal to datum value: %v; received: %T", allowedTypes, datum)
+		}
+	}
+
+	if config.InferUnionBranch {
+		// NOTE: Wrap the encoders so a bare datum value is wrapped in a map
+		// keyed by the name of the union member selected to encode it, before
+		// handing it to the regular union encoders.
+		// inferBranch returns datum wrapped for the member selected to encode
+		// it, and true when that member was selected by inference. When
+		// untested, the only member whose shape matches datum is selected
+		// without testing it can encode datum.
+		inferBranch := func(datum interface{}, untested bool) (interface{}, bool, error) {
+			if datum = unionDatum(datum); datum == nil {
+				return nil, false, nil // null member is handled by regular encoders
+			}
+			// NOTE: A map with a single key equal to a member name is always
+			// taken to be an already wrapped value, even when another member
+			// could encode the map itself, so values decoded by the union
+			// decoders are encoded to the same member when encoded again.
+			if v, ok := datum.(map[string]interface{}); ok && len(v) == 1 {
+				for key := range v {
+					if _, ok := indexFromName[key]; ok {
+						return datum, false, nil // already wrapped
+					}
+				}
+			}
+			index, err := unionIndexFromNative(codecFromIndex, allowedTypes, datum, untested)
+			if err != nil {
+				return nil, false, err
+			}
+			return Union(allowedTypes[index], datum), true, nil
+		}
+
+		binaryFromNative, textualFromNative := c.binaryFromNative, c.textualFromNative
+
+		c.binaryFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+			wrapped, inferred, err := inferBranch(datum, true)
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode binary union: %s", err)
+			}
+			if buf, err = binaryFromNative(buf, wrapped); err != nil && inferred {
+				// NOTE: The only member whose shape matches datum is selected
+				// without first being tested, so it may not support datum.
+				return nil, fmt.Errorf("cannot encode binary union: no member schema types support datum: allowed types: %v; received: %T; %s", allowedTypes, unionDatum(datum), err)
+			}
+			return buf, err
+		}
+		c.textualFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+			// NOTE: Textual encoders do not test all values the binary
+			// encoders do, such as the range of int values, so always test
+			// the selected member using its binary encoder.
+			wrapped, _, err := inferBranch(datum, false)
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode textual union: %s", err)
+			}
+			return textualFromNative(buf, wrapped)
+		}
+	}
+
+	return c, nil
+}
+
+// unionIndexFromNative returns the index of the union member that ought to
+// encode the non-nil datum, based on the Go type of datum. A union member whose
+// type exactly matches the Go type of datum is always selected. Otherwise, the
+// union member that is able to encode datum is selected. When more than one
+// member is able to encode datum, and all of them are numeric, the first one
+// is selected, in accordance with Avro numeric type promotion. Otherwise the
+// datum is ambiguous, and an error is returned.
+//
+// To avoid encoding datum with every member, members that cannot encode datum
+// because of its shape are ruled out first. When untested, and only one member
+// remains, it is selected without encoding datum, leaving the caller to report
+// any error when it encodes datum.
+func unionIndexFromNative(codecFromIndex []*Codec, allowedTypes []string, datum interface{}, untested bool) (int, error) {
+	var exact string
+	switch datum.(type) {
+	case bool:
+		exact = "boolean"
+	case []byte:
+		exact = "bytes"
+	case float32:
+		exact = "float"
+	case float64:
+		exact = "double"
+	case int32:
+		exact = "int"
+	case int, int64:
+		exact = "long"
+	case string:
+		exact = "string"
+	}
+	if exact != "" {
+		for index, name := range allowedTypes {
+			if name == exact {
+				return index, nil
+			}
+		}
+	}
+
+	var shaped []int
+	for index, memberCodec := range codecFromIndex {
+		if mayEncode(memberCodec, datum) {
+			shaped = append(shaped, index)
+		}
+	}
+	if untested && len(shaped) == 1 {
+		return shaped[0], nil
+	}
+
+	var candidates []int
+	for _, index := range shaped {
+		if _, err := codecFromIndex[index].binaryFromNative(nil, datum); err == nil {
+			candidates = append(candidates, index)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return 0, fmt.Errorf("no member schema types support datum: allowed types: %v; received: %T", allowedTypes, datum)
+	case 1:
+		return candidates[0], nil
+	}
+	for _, index := range candidates {
+		switch allowedTypes[index] {
+		case "int", "long", "float", "double":
+			// numeric types may be promoted
+		default:
+			names := make([]string, len(candidates))
+			for i, index := range candidates {
+				names[i] = allowedTypes[index]
+			}
+			return 0, fmt.Errorf("more than one member schema type supports datum: %v; received: %T", names, datum)
+		}
+	}
+	return candidates[0], nil
+}
+
+// mayEncode returns false when the shape of the non-nil datum prevents c from
+// encoding it, such as a record datum missing a field that has no default
+// value. Otherwise it returns true, even though c may still fail to encode it.
+func mayEncode(c *Codec, datum interface{}) bool {
+	switch c.avroType() {
+	case "null":
+		return false
+	case "boolean":
+		_, ok := datum.(bool)
+		return ok
+	case "enum":
+		_, ok := datum.(string)
+		return ok
+	case "fixed":
+		_, ok := datum.([]byte)
+		return ok
+	case "array":
+		return reflect.ValueOf(datum).Kind() == reflect.Slice
+	case "map":
+		return reflect.ValueOf(datum).Kind() == reflect.Map
+	case "record":
+		values, ok := datum.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for _, field := range c.fields {
+			if _, ok := values[field.name]; !ok && !field.hasDefault {
+				return false
+			}
+		}
+	}
+	return true
 }
@@ -152,6 +152,232 @@ func TestUnionText(t *testing.T) {
 	testTextCodecPass(t, `["null","int","string"]`, goavro.Union("string", "😂 "), []byte(`{"string":"\u0001\uD83D\uDE02 "}`))
 }
 
+func TestUnionJSON(t *testing.T) {
+	testJSONCodecPass(t, `["null","int"]`, goavro.CodecConfig{}, goavro.Union("null", nil), []byte("null"))
+	testJSONCodecPass(t, `["null","int"]`, goavro.CodecConfig{}, goavro.Union("int", 3), []byte("3"))
+	testJSONCodecPass(t, `["null","int","string"]`, goavro.CodecConfig{}, goavro.Union("string", "some string"), []byte(`"some string"`))
+
+	// JSON number with fraction cannot be decoded as int, so next member wins
+	testJSONCodecPass(t, `["int","double"]`, goavro.CodecConfig{}, goavro.Union("double", 3.5), []byte("3.5"))
+	// when more than one member can decode the value, the first one wins
+	testJSONCodecPass(t, `["double","int"]`, goavro.CodecConfig{}, goavro.Union("double", 3.0), []byte("3"))
+
+	testJSONCodecPass(t, `["string",{"type":"array","items":"int"}]`, goavro.CodecConfig{}, goavro.Union("array", []interface{}{1, 2}), []byte("[1,2]"))
+
+	testJSONDecodeFail(t, `["null","int"]`, []byte(`"some string"`), "no member schema types support datum")
+}
+
+func TestUnionJSONRecordOrMap(t *testing.T) {
+	schema := `{"type":"record","name":"r1","fields":[{"name":"f1","type":["null",{"type":"record","name":"com.example.r2","fields":[{"name":"f2","type":"bytes"}]},{"type":"map","values":"int"}]}]}`
+
+	testJSONCodecPass(t, schema, goavro.CodecConfig{}, map[string]interface{}{"f1": nil}, []byte(`{"f1":null}`))
+	testJSONCodecPass(t, schema, goavro.CodecConfig{}, map[string]interface{}{"f1": goavro.Union("com.example.r2", map[string]interface{}{"f2": []byte("hi")})}, []byte(`{"f1":{"f2":"aGk="}}`))
+	// keys that are not record fields can only be decoded by the map
+	testJSONCodecPass(t, schema, goavro.CodecConfig{}, map[string]interface{}{"f1": goavro.Union("map", map[string]interface{}{"k1": 13})}, []byte(`{"f1":{"k1":13}}`))
+}
+
+func testUnionInferBranchPass(t *testing.T, schema string, datum interface{}, expectedBinary, expectedText []byte) {
+	codec, err := goavro.NewCodecWithConfig(schema, goavro.CodecConfig{InferUnionBranch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := codec.BinaryFromNative(nil, datum)
+	if err != nil {
+		t.Fatalf("schema: %s; Datum: %v; %s", schema, datum, err)
+	}
+	if !bytes.Equal(buf, expectedBinary) {
+		t.Errorf("schema: %s; Datum: %v; Actual: %#v; Expected: %#v", schema, datum, buf, expectedBinary)
+	}
+	buf, err = codec.TextualFromNative(nil, datum)
+	if err != nil {
+		t.Fatalf("schema: %s; Datum: %v; %s", schema, datum, err)
+	}
+	if !bytes.Equal(buf, expectedText) {
+		t.Errorf("schema: %s; Datum: %v; Actual: %s; Expected: %s", schema, datum, buf, expectedText)
+	}
+}
+
+func testUnionInferBranchFail(t *testing.T, schema string, datum interface{}, expected ...string) {
+	codec, err := goavro.NewCodecWithConfig(schema, goavro.CodecConfig{InferUnionBranch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = codec.BinaryFromNative(nil, datum)
+	ensureError(t, err, expected...)
+	_, err = codec.TextualFromNative(nil, datum)
+	ensureError(t, err, expected...)
+}
+
+func TestUnionInferBranch(t *testing.T) {
+	schema := `["null","int","long","float","double","string","bytes","boolean"]`
+	testUnionInferBranchPass(t, schema, nil, []byte("\x00"), []byte("null"))
+	testUnionInferBranchPass(t, schema, int32(3), []byte("\x02\x06"), []byte(`{"int":3}`))
+	testUnionInferBranchPass(t, schema, int64(3), []byte("\x04\x06"), []byte(`{"long":3}`))
+	testUnionInferBranchPass(t, schema, 3, []byte("\x04\x06"), []byte(`{"long":3}`))
+	testUnionInferBranchPass(t, schema, float32(3.5), []byte("\x06\x00\x00\x60\x40"), []byte(`{"float":3.5}`))
+	testUnionInferBranchPass(t, schema, "abc", []byte("\x0a\x06abc"), []byte(`{"string":"abc"}`))
+	testUnionInferBranchPass(t, schema, []byte("abc"), []byte("\x0c\x06abc"), []byte(`{"bytes":"abc"}`))
+	testUnionInferBranchPass(t, schema, true, []byte("\x0e\x01"), []byte(`{"boolean":true}`))
+
+	// values wrapped by Union are still accepted
+	testUnionInferBranchPass(t, schema, goavro.Union("double", 3), []byte("\x08\x00\x00\x00\x00\x00\x00\x08\x40"), []byte(`{"double":3}`))
+}
+
+func TestUnionInferBranchPromotion(t *testing.T) {
+	// without exact match, first numeric member able to encode datum is used
+	testUnionInferBranchPass(t, `["null","long","double"]`, int32(3), []byte("\x02\x06"), []byte(`{"long":3}`))
+	testUnionInferBranchPass(t, `["null","double","long"]`, int32(3), []byte("\x02\x00\x00\x00\x00\x00\x00\x08\x40"), []byte(`{"double":3}`))
+	testUnionInferBranchFail(t, `["null","int"]`, int64(math.MaxInt64), "no member schema types support datum")
+}
+
+func TestUnionInferBranchNamedTypes(t *testing.T) {
+	schema := `["null",{"type":"record","name":"com.example.point","fields":[{"name":"x","type":"int"},{"name":"y","type":"int"}]},{"type":"enum","name":"color","symbols":["red","green"]},{"type":"array","items":"string"}]`
+	testUnionInferBranchPass(t, schema, map[string]interface{}{"x": 1, "y": 2}, []byte("\x02\x02\x04"), []byte(`{"com.example.point":{"x":1,"y":2}}`))
+	testUnionInferBranchPass(t, schema, "green", []byte("\x04\x02"), []byte(`{"color":"green"}`))
+	testUnionInferBranchPass(t, schema, []string{"a"}, []byte("\x06\x02\x02a\x00"), []byte(`{"array":["a"]}`))
+	testUnionInferBranchFail(t, schema, "blue", "no member schema types support datum")
+	testUnionInferBranchFail(t, schema, map[string]interface{}{"x": 1}, "no member schema types support datum")
+
+	ambiguous := `[{"type":"record","name":"r1","fields":[{"name":"f1","type":"string"}]},{"type":"map","values":"string"}]`
+	testUnionInferBranchFail(t, ambiguous, map[string]interface{}{"f1": "v1"}, "more than one member schema type supports datum", "r1", "map")
+}
+
+func TestUnionDecoding(t *testing.T) {
+	schema := `{"type":"record","name":"r1","fields":[{"name":"f1","type":["null","int"]},{"name":"f2","type":{"type":"array","items":["null","string"]}},{"name":"f3","type":{"type":"map","values":["null","long"]}},{"name":"f4","type":["string","null"],"default":"d4"}]}`
+	binary := []byte("\x02\x06\x04\x02\x02a\x00\x00\x02\x04k1\x02\x08\x00\x00\x04d4")
+	text := []byte(`{"f1":{"int":3},"f2":[{"string":"a"},null],"f3":{"k1":{"long":4}}}`)
+
+	for _, tc := range []struct {
+		unionDecoding string
+		expected      string
+	}{
+		{"", `map[f1:map[int:3] f2:[map[string:a] <nil>] f3:map[k1:map[long:4]] f4:map[string:d4]]`},
+		{goavro.UnionDecodingMapLabel, `map[f1:map[int:3] f2:[map[string:a] <nil>] f3:map[k1:map[long:4]] f4:map[string:d4]]`},
+		{goavro.UnionDecodingBareLabel, `map[f1:3 f2:[a <nil>] f3:map[k1:4] f4:d4]`},
+		{goavro.UnionDecodingValueLabel, `map[f1:{int 3} f2:[{string a} <nil>] f3:map[k1:{long 4}] f4:{string d4}]`},
+	} {
+		codec, err := goavro.NewCodecWithConfig(schema, goavro.CodecConfig{UnionDecoding: tc.unionDecoding})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		datum, _, err := codec.NativeFromBinary(binary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := fmt.Sprintf("%v", datum); actual != tc.expected {
+			t.Errorf("binary: %q; Actual: %v; Expected: %v", tc.unionDecoding, actual, tc.expected)
+		}
+
+		datum, _, err = codec.NativeFromTextual(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := fmt.Sprintf("%v", datum); actual != tc.expected {
+			t.Errorf("textual: %q; Actual: %v; Expected: %v", tc.unionDecoding, actual, tc.expected)
+		}
+	}
+
+	_, err := goavro.NewCodecWithConfig(schema, goavro.CodecConfig{UnionDecoding: "flat"})
+	ensureError(t, err, "unrecognized union decoding")
+}
+
+func TestUnionValueRoundTrip(t *testing.T) {
+	codec, err := goavro.NewCodecWithConfig(`["null","string","long"]`, goavro.CodecConfig{UnionDecoding: goavro.UnionDecodingValueLabel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	datum, _, err := codec.NativeFromBinary([]byte("\x04\x06"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := datum, (goavro.UnionValue{Branch: "long", Value: int64(3)}); actual != expected {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+	buf, err := codec.BinaryFromNative(nil, datum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := buf, []byte("\x04\x06"); !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+	buf, err = codec.TextualFromNative(nil, datum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := string(buf), `{"long":3}`; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+}
+
+func TestUnionDecodingBareRoundTrip(t *testing.T) {
+	codec, err := goavro.NewCodecWithConfig(`["null","string","long"]`, goavro.CodecConfig{UnionDecoding: goavro.UnionDecodingBareLabel, InferUnionBranch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	datum, _, err := codec.NativeFromJSON([]byte(`"some string"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := datum, "some string"; actual != expected {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+	buf, err := codec.BinaryFromNative(nil, datum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := buf, []byte("\x02\x16some string"); !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+}
+
+func TestUnionShortNames(t *testing.T) {
+	schema := `["null",{"type":"record","name":"com.acme.Address","fields":[{"name":"city","type":"string"}]},{"type":"enum","name":"com.acme.Color","symbols":["red"]},{"type":"enum","name":"org.other.Color","symbols":["blue"]}]`
+
+	codec, err := goavro.NewCodecWithConfig(schema, goavro.CodecConfig{UnionShortNames: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := codec.BinaryFromNative(nil, goavro.Union("Address", map[string]interface{}{"city": "Paris"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := buf, []byte("\x02\x0aParis"); !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+
+	// textual encoding always uses the full name
+	buf, err = codec.TextualFromNative(nil, goavro.Union("Address", map[string]interface{}{"city": "Paris"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := string(buf), `{"com.acme.Address":{"city":"Paris"}}`; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	datum, _, err := codec.NativeFromTextual([]byte(`{"Address":{"city":"Paris"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := fmt.Sprintf("%v", datum), "map[com.acme.Address:map[city:Paris]]"; actual != expected {
+		t.Errorf("Actual: %v; Expected: %v", actual, expected)
+	}
+
+	// short name is ambiguous, but full names are still accepted
+	_, err = codec.BinaryFromNative(nil, goavro.Union("Color", "red"))
+	ensureError(t, err, "no member schema types support datum")
+	_, _, err = codec.NativeFromTextual([]byte(`{"Color":"red"}`))
+	ensureError(t, err, "cannot determine codec")
+	_, err = codec.BinaryFromNative(nil, goavro.Union("org.other.Color", "blue"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// short names not accepted unless configured
+	testBinaryEncodeFail(t, schema, goavro.Union("Address", map[string]interface{}{"city": "Paris"}), "no member schema types support datum")
+}
+
 func ExampleUnion() {
 	codec, err := goavro.NewCodec(`["null","string","int"]`)
 	if err != nil {
@@ -213,3 +439,40 @@ func ExampleUnion3() {
 	fmt.Println(value)
 	// Output: decoded string: NaN
 }
+
+func TestUnionInferBranchWrappedPrecedence(t *testing.T) {
+	// a map whose single key is a member name is taken to be wrapped
+	schema := `["string",{"type":"map","values":"string"}]`
+	testUnionInferBranchPass(t, schema, map[string]interface{}{"string": "x"}, []byte("\x00\x02x"), []byte(`{"string":"x"}`))
+	testUnionInferBranchPass(t, schema, map[string]interface{}{"k": "x"}, []byte("\x02\x02\x02k\x02x\x00"), []byte(`{"map":{"k":"x"}}`))
+}
+
+func TestUnionInferBranchRecordShape(t *testing.T) {
+	schema := `["null",{"type":"record","name":"r1","fields":[{"name":"a","type":"long"}]},{"type":"record","name":"r2","fields":[{"name":"b","type":"string"}]}]`
+	codec, err := goavro.NewCodecWithConfig(schema, goavro.CodecConfig{InferUnionBranch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	datum := map[string]interface{}{"b": "x"}
+	buf, err := codec.BinaryFromNative(nil, datum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := buf, []byte("\x04\x02x"); !bytes.Equal(actual, expected) {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+
+	// Selecting the member from the shape of datum ought not encode datum
+	// with each member before encoding it.
+	buf = make([]byte, 0, 64)
+	wrapped := goavro.Union("r2", datum)
+	wrappedAllocs := testing.AllocsPerRun(100, func() {
+		_, _ = codec.BinaryFromNative(buf[:0], wrapped)
+	})
+	inferredAllocs := testing.AllocsPerRun(100, func() {
+		_, _ = codec.BinaryFromNative(buf[:0], datum)
+	})
+	if actual, limit := inferredAllocs, wrappedAllocs+2; actual > limit {
+		t.Errorf("Actual: %v; Expected: at most %v", actual, limit)
+	}
+}